@@ -27,7 +27,11 @@ class BenchmarkData:
 def row_iterator(path: str) -> Generator[dict, None, None]:
     with open(path, 'r') as f:
         for line in f:
-            yield json.loads(line)
+            try:
+                yield json.loads(line)
+            except json.JSONDecodeError:
+                # a line cut off by a crash, which a resumed run terminated
+                continue
 
 
 def load_benchmark_log(path: str) -> BenchmarkData:
@@ -47,7 +51,15 @@ def load_benchmark_log(path: str) -> BenchmarkData:
         timestamp = row.get('time')
 
         if msg == 'starting run':
-            init_data = row
+            # a resumed run logs another 'starting run', the first one describes the whole run
+            if init_data is None:
+                init_data = row
+        elif msg == 'resumed run':
+            # versions after the resumed version were not persisted and are applied again by the resumed run
+            resumed_version = row['resumed_version']
+            version_rows = [r for r in version_rows if r['version'] <= resumed_version]
+            mem_rows = [r for r in mem_rows if r['version'] <= resumed_version]
+            disk_rows = [r for r in disk_rows if r['version'] <= resumed_version]
         elif msg == 'benchmark run complete':
             run_complete_time = timestamp
         elif msg == 'committed version':
@@ -55,6 +67,10 @@ def load_benchmark_log(path: str) -> BenchmarkData:
                 'version': row['version'],
                 'timestamp': timestamp,
                 'duration': row['duration'],
+                # older logs only record the total duration
+                'decode_duration': row.get('decode_duration'),
+                'apply_duration': row.get('apply_duration'),
+                'commit_duration': row.get('commit_duration'),
                 'count': row['count'],
                 'ops_per_sec': row['ops_per_sec'],
             })
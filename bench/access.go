@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/tidwall/btree"
+)
+
+// Key access distributions of StoreParams.AccessDistribution.
+const (
+	// AccessUniform targets every existing key with the same probability.
+	AccessUniform = "uniform"
+	// AccessZipf targets the existing keys with a Zipf distribution over their age, like a few hot accounts
+	// and pools taking most of the updates on a real chain.
+	AccessZipf = "zipf"
+	// AccessHotSet targets the oldest HotSetFraction of the existing keys with HotSetProbability and
+	// the other keys uniformly otherwise.
+	AccessHotSet = "hotset"
+	// AccessRecent targets recently created keys more, with the number of keys created after a targeted key
+	// being exponentially distributed with mean RecencyMean.
+	AccessRecent = "recent"
+)
+
+// accessPicker returns the function selecting the age rank of the targeted key out of n existing keys,
+// 0 being the oldest key, or nil for uniform access.
+func (c StoreParams) accessPicker() (func(rng *rand.Rand, n int) int, error) {
+	switch c.AccessDistribution {
+	case "", AccessUniform:
+		return nil, nil
+	case AccessZipf:
+		if c.ZipfSkew <= 1 {
+			return nil, fmt.Errorf("zipf skew must be > 1, got %g", c.ZipfSkew)
+		}
+		return func(rng *rand.Rand, n int) int {
+			return int(rand.NewZipf(rng, c.ZipfSkew, 1, uint64(n-1)).Uint64())
+		}, nil
+	case AccessHotSet:
+		if c.HotSetFraction <= 0 || c.HotSetFraction >= 1 {
+			return nil, fmt.Errorf("hot set fraction must be in (0, 1), got %g", c.HotSetFraction)
+		}
+		if c.HotSetProbability < 0 || c.HotSetProbability > 1 {
+			return nil, fmt.Errorf("hot set probability must be in [0, 1], got %g", c.HotSetProbability)
+		}
+		return func(rng *rand.Rand, n int) int {
+			hot := max(1, int(c.HotSetFraction*float64(n)))
+			if hot >= n || rng.Float64() < c.HotSetProbability {
+				return rng.IntN(hot)
+			}
+			return hot + rng.IntN(n-hot)
+		}, nil
+	case AccessRecent:
+		if c.RecencyMean <= 0 {
+			return nil, fmt.Errorf("recency mean must be positive, got %g", c.RecencyMean)
+		}
+		return func(rng *rand.Rand, n int) int {
+			newer := int(rng.ExpFloat64() * c.RecencyMean)
+			if newer >= n {
+				// the tail beyond the oldest key is spread over all keys
+				newer = rng.IntN(n)
+			}
+			return n - 1 - newer
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown access distribution %q, expected one of %s, %s, %s or %s",
+			c.AccessDistribution, AccessUniform, AccessZipf, AccessHotSet, AccessRecent)
+	}
+}
+
+// keyAges orders the existing keys of a store by creation for the non-uniform access distributions.
+// Its methods are no-ops on a nil keyAges.
+type keyAges struct {
+	pickRank func(rng *rand.Rand, n int) int
+	// byAge maps the creation sequence number of every existing key to the key
+	byAge *btree.Map[uint64, []byte]
+	seqs  map[string]uint64
+	next  uint64
+}
+
+func newKeyAges(pickRank func(rng *rand.Rand, n int) int) *keyAges {
+	return &keyAges{
+		pickRank: pickRank,
+		byAge:    &btree.Map[uint64, []byte]{},
+		seqs:     map[string]uint64{},
+	}
+}
+
+func (a *keyAges) add(key []byte) {
+	if a == nil {
+		return
+	}
+	a.byAge.Set(a.next, key)
+	a.seqs[string(key)] = a.next
+	a.next++
+}
+
+func (a *keyAges) delete(key []byte) {
+	if a == nil {
+		return
+	}
+	seq, ok := a.seqs[string(key)]
+	if !ok {
+		return
+	}
+	a.byAge.Delete(seq)
+	delete(a.seqs, string(key))
+}
+
+func (a *keyAges) pick(rng *rand.Rand) ([]byte, bool) {
+	n := a.byAge.Len()
+	if n == 0 {
+		return nil, false
+	}
+	_, key, ok := a.byAge.GetAt(a.pickRank(rng, n))
+	return key, ok
+}
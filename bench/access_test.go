@@ -0,0 +1,168 @@
+package bench
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestAccessPickerValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  StoreParams
+		wantErr bool
+	}{
+		{name: "default", params: StoreParams{}},
+		{name: "uniform", params: StoreParams{AccessDistribution: AccessUniform}},
+		{name: "zipf", params: StoreParams{AccessDistribution: AccessZipf, ZipfSkew: 1.1}},
+		{name: "zipf skew of 1", params: StoreParams{AccessDistribution: AccessZipf, ZipfSkew: 1}, wantErr: true},
+		{name: "hotset", params: StoreParams{AccessDistribution: AccessHotSet, HotSetFraction: 0.1, HotSetProbability: 1}},
+		{name: "hotset fraction of 0", params: StoreParams{AccessDistribution: AccessHotSet, HotSetProbability: 0.5}, wantErr: true},
+		{name: "hotset fraction of 1", params: StoreParams{AccessDistribution: AccessHotSet, HotSetFraction: 1, HotSetProbability: 0.5}, wantErr: true},
+		{name: "hotset probability above 1", params: StoreParams{AccessDistribution: AccessHotSet, HotSetFraction: 0.1, HotSetProbability: 1.1}, wantErr: true},
+		{name: "recent", params: StoreParams{AccessDistribution: AccessRecent, RecencyMean: 0.5}},
+		{name: "recent mean of 0", params: StoreParams{AccessDistribution: AccessRecent}, wantErr: true},
+		{name: "unknown", params: StoreParams{AccessDistribution: "normal"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pick, err := tt.params.accessPicker()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("accessPicker() error = %v, want error %t", err, tt.wantErr)
+			}
+			uniform := tt.params.AccessDistribution == "" || tt.params.AccessDistribution == AccessUniform
+			if err == nil && (pick == nil) != uniform {
+				t.Errorf("accessPicker() returned a nil picker %t, want %t", pick == nil, uniform)
+			}
+		})
+	}
+}
+
+// pickCounts returns how often each rank out of n is picked in samples picks.
+func pickCounts(t *testing.T, params StoreParams, n, samples int) []int {
+	t.Helper()
+	pick, err := params.accessPicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	counts := make([]int, n)
+	for range samples {
+		rank := pick(rng, n)
+		if rank < 0 || rank >= n {
+			t.Fatalf("picked rank %d out of %d keys", rank, n)
+		}
+		counts[rank]++
+	}
+	return counts
+}
+
+func sumCounts(counts []int) int {
+	res := 0
+	for _, c := range counts {
+		res += c
+	}
+	return res
+}
+
+func TestAccessHotSet(t *testing.T) {
+	const samples = 100_000
+	params := StoreParams{AccessDistribution: AccessHotSet, HotSetFraction: 0.1, HotSetProbability: 0.9}
+	counts := pickCounts(t, params, 1000, samples)
+	// the hot set is the 100 oldest keys
+	if hot := float64(sumCounts(counts[:100])) / samples; math.Abs(hot-0.9) > 0.01 {
+		t.Errorf("hot set share %.3f, want 0.9", hot)
+	}
+	// the rest of the keys are targeted uniformly
+	low, high := sumCounts(counts[100:550]), sumCounts(counts[550:])
+	if ratio := float64(low) / float64(high); math.Abs(ratio-1) > 0.05 {
+		t.Errorf("cold keys are not uniform, halves have %d and %d picks", low, high)
+	}
+
+	// the hot set has at least one key
+	counts = pickCounts(t, params, 5, samples)
+	if hot := float64(counts[0]) / samples; math.Abs(hot-0.9) > 0.01 {
+		t.Errorf("hot set share of 5 keys %.3f, want 0.9", hot)
+	}
+	// a single key is always hot
+	if counts := pickCounts(t, params, 1, 100); counts[0] != 100 {
+		t.Errorf("picked a single key %d of 100 times", counts[0])
+	}
+}
+
+func TestAccessZipf(t *testing.T) {
+	const samples = 100_000
+	const n = 1000
+	params := StoreParams{AccessDistribution: AccessZipf, ZipfSkew: 1.5}
+	counts := pickCounts(t, params, n, samples)
+	// the probability of rank k is proportional to (1 + k)^-skew
+	var norm float64
+	for k := range n {
+		norm += math.Pow(float64(1+k), -1.5)
+	}
+	for _, k := range []int{0, 1, 2, 10} {
+		want := math.Pow(float64(1+k), -1.5) / norm
+		if got := float64(counts[k]) / samples; math.Abs(got-want) > 0.01 {
+			t.Errorf("rank %d share %.4f, want %.4f", k, got, want)
+		}
+	}
+	if counts[0] <= counts[1] || counts[1] <= counts[10] || counts[10] <= counts[500] {
+		t.Errorf("picks don't decrease with age rank: %d, %d, %d, %d", counts[0], counts[1], counts[10], counts[500])
+	}
+}
+
+func TestAccessRecent(t *testing.T) {
+	const samples = 100_000
+	const n = 10_000
+	params := StoreParams{AccessDistribution: AccessRecent, RecencyMean: 10}
+	counts := pickCounts(t, params, n, samples)
+	var newer float64
+	for rank, c := range counts {
+		newer += float64((n-1-rank)*c) / samples
+	}
+	// the mean of the exponential distribution rounded down, 1 / (e^(1/mean) - 1)
+	want := 1 / (math.Exp(0.1) - 1)
+	if math.Abs(newer-want) > 0.2 {
+		t.Errorf("mean number of newer keys %.2f, want %.2f", newer, want)
+	}
+	if newest := float64(counts[n-1]) / samples; math.Abs(newest-(1-math.Exp(-0.1))) > 0.01 {
+		t.Errorf("newest key share %.3f, want %.3f", newest, 1-math.Exp(-0.1))
+	}
+
+	// a mean beyond the number of keys spreads the tail over all keys
+	params.RecencyMean = 1e6
+	counts = pickCounts(t, params, 10, samples)
+	for rank, c := range counts {
+		if share := float64(c) / samples; math.Abs(share-0.1) > 0.01 {
+			t.Errorf("rank %d share %.3f, want about 0.1", rank, share)
+		}
+	}
+}
+
+func TestKeyAges(t *testing.T) {
+	var rank int
+	ages := newKeyAges(func(rng *rand.Rand, n int) int { return rank })
+	rng := rand.New(rand.NewPCG(1, 2))
+	if _, ok := ages.pick(rng); ok {
+		t.Error("picked a key without keys")
+	}
+	for _, key := range []string{"c", "a", "b"} {
+		ages.add([]byte(key))
+	}
+	ages.delete([]byte("c"))
+	ages.delete([]byte("unknown"))
+	ages.add([]byte("c"))
+	// ranked by creation, the deleted and recreated key being the newest
+	for i, want := range []string{"a", "b", "c"} {
+		rank = i
+		key, ok := ages.pick(rng)
+		if !ok || string(key) != want {
+			t.Errorf("rank %d picked %q, want %q", i, key, want)
+		}
+	}
+
+	// a nil keyAges is a no-op
+	var none *keyAges
+	none.add([]byte("a"))
+	none.delete([]byte("a"))
+}
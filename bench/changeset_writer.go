@@ -0,0 +1,124 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+
+	storev1beta1 "cosmossdk.io/api/cosmos/store/v1beta1"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// ChangesetWriter writes changesets which are not generated, e.g. imported from a chain, to a changeset dir
+// one version at a time, starting at version 1. The changeset info file is rewritten after every version
+// so that the versions written so far can be run if writing stops midway.
+type ChangesetWriter struct {
+	outDir      string
+	source      string
+	startHeight int64
+	// version is the version currently being written
+	version    int64
+	file       *os.File
+	writer     *bufio.Writer
+	storeNames map[string]bool
+}
+
+// NewChangesetWriter creates the changeset dir outDir, which must not exist yet. source describes where the
+// changesets come from and startHeight is the block height of version 1, or 0 if unknown.
+func NewChangesetWriter(outDir, source string, startHeight int64) (*ChangesetWriter, error) {
+	_, err := os.Stat(outDir)
+	if err == nil {
+		return nil, fmt.Errorf("output directory %s already exists", outDir)
+	}
+	err = os.MkdirAll(outDir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangesetWriter{
+		outDir:      outDir,
+		source:      source,
+		startHeight: startHeight,
+		version:     1,
+		storeNames:  map[string]bool{},
+	}, nil
+}
+
+// Version returns the version which is currently being written.
+func (w *ChangesetWriter) Version() int64 {
+	return w.version
+}
+
+func (w *ChangesetWriter) ensureFile() error {
+	if w.file != nil {
+		return nil
+	}
+	f, err := os.Create(changesetDataFilename(w.outDir, w.version))
+	if err != nil {
+		return fmt.Errorf("error creating changeset file for version %d: %w", w.version, err)
+	}
+	w.file = f
+	w.writer = bufio.NewWriter(f)
+	return nil
+}
+
+// Write appends a change to the current version.
+func (w *ChangesetWriter) Write(pair *storev1beta1.StoreKVPair) error {
+	err := w.ensureFile()
+	if err != nil {
+		return err
+	}
+	w.storeNames[pair.StoreKey] = true
+	_, err = protodelim.MarshalTo(w.writer, pair)
+	if err != nil {
+		return fmt.Errorf("error writing changeset for version %d: %w", w.version, err)
+	}
+	return nil
+}
+
+// Commit completes the current version, which may be empty, and starts the next one.
+func (w *ChangesetWriter) Commit() error {
+	err := w.ensureFile()
+	if err != nil {
+		return err
+	}
+	err = w.writer.Flush()
+	if err != nil {
+		return fmt.Errorf("error writing changeset for version %d: %w", w.version, err)
+	}
+	err = w.file.Close()
+	if err != nil {
+		return fmt.Errorf("error closing changeset file for version %d: %w", w.version, err)
+	}
+	w.file, w.writer = nil, nil
+
+	storeNames := make([]string, 0, len(w.storeNames))
+	for name := range w.storeNames {
+		storeNames = append(storeNames, name)
+	}
+	sort.Strings(storeNames)
+	err = writeChangesetInfo(w.outDir, changesetInfo{
+		Versions:    w.version,
+		StoreNames:  storeNames,
+		Source:      w.source,
+		StartHeight: w.startHeight,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing changeset info file: %w", err)
+	}
+	w.version++
+	return nil
+}
+
+// Close discards the changes written since the last Commit.
+func (w *ChangesetWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	if err != nil {
+		return err
+	}
+	w.file, w.writer = nil, nil
+	return os.Remove(changesetDataFilename(w.outDir, w.version))
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand/v2"
+	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/tidwall/jsonc"
 
 	"github.com/cosmos/iavl-bench/bench"
 )
@@ -18,28 +21,124 @@ func MixedGenerators(versions int64, scale float64) []bench.StoreParams {
 	return gens
 }
 
+// StructuredGenerators are the mixed generators with keys shaped like those of the corresponding Cosmos SDK stores.
+func StructuredGenerators(versions int64, scale float64) []bench.StoreParams {
+	bank := bench.BankLikeGenerator(versions, scale)
+	// balances: 0x02 | len | address | denom, with enough addresses for twice the final size
+	bank.KeySchema = []bench.KeySegment{
+		{Type: bench.KeySegmentFixed, Hex: "02"},
+		{Type: bench.KeySegmentAddress, Population: max(1, bank.FinalSize/2), LengthPrefixed: true},
+		{Type: bench.KeySegmentChoice, Values: []string{
+			"uosmo",
+			"uion",
+			"ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2",
+			"ibc/D189335C6E4A68B513C10AB227BF1C1D38C746766278BA3EEB4FB14124F1D858",
+		}},
+	}
+	staking := bench.StakingLikeGenerator(versions, scale)
+	// delegations: 0x31 | len | delegator address | len | validator address
+	staking.KeySchema = []bench.KeySegment{
+		{Type: bench.KeySegmentFixed, Hex: "31"},
+		{Type: bench.KeySegmentAddress, Population: max(1, staking.FinalSize/75), LengthPrefixed: true},
+		{Type: bench.KeySegmentAddress, Population: 150, LengthPrefixed: true},
+	}
+	lockup := bench.LockupLikeGenerator(versions, scale)
+	// locks by sequential id: 0x01 | id
+	lockup.KeySchema = []bench.KeySegment{
+		{Type: bench.KeySegmentFixed, Hex: "01"},
+		{Type: bench.KeySegmentCounter},
+	}
+	return []bench.StoreParams{bank, staking, lockup}
+}
+
+// readStoreParams reads a JSON/JSONC file holding a list of store params. Store params without versions
+// get the number of versions to generate.
+func readStoreParams(path string, versions int64) ([]bench.StoreParams, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading store params file: %w", err)
+	}
+	var gens []bench.StoreParams
+	err = json.Unmarshal(jsonc.ToJSON(bz), &gens)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling store params file: %w", err)
+	}
+	for i := range gens {
+		if gens[i].Versions == 0 {
+			gens[i].Versions = versions
+		}
+	}
+	return gens, nil
+}
+
 func main() {
 	var versions int64
 	var profile string
 	var scale float64
+	var access bench.StoreParams
+	var values bench.StoreParams
+	var storeParamsFile string
 	cmd := &cobra.Command{
 		Use:   "gen-changesets [out-dir]",
 		Short: "Generate changesets for iavl-bench",
 		Args:  cobra.ExactArgs(1),
 	}
 	cmd.Flags().Int64Var(&versions, "versions", 100, "number of versions to generate")
-	cmd.Flags().StringVar(&profile, "profile", "mixed", "data generation profile to use (mixed|osmo); default is small")
+	cmd.Flags().StringVar(&profile, "profile", "mixed", "data generation profile to use (mixed|structured|osmo); default is small")
 	cmd.Flags().Float64Var(&scale, "scale", 1.0, "float64 scale factor for the profile; default is 1.0")
+	cmd.Flags().StringVar(&storeParamsFile, "store-params", "", "if set, a JSON/JSONC file with a list of store params, e.g. with key schemas, to use instead of the profile")
+	cmd.Flags().StringVar(&access.AccessDistribution, "access", "",
+		fmt.Sprintf("if set, the distribution of the keys targeted by updates and deletes in all stores (%s|%s|%s|%s); default is uniform",
+			bench.AccessUniform, bench.AccessZipf, bench.AccessHotSet, bench.AccessRecent))
+	cmd.Flags().Float64Var(&access.ZipfSkew, "zipf-skew", 1.1, "skew of the zipf access distribution, must be > 1")
+	cmd.Flags().Float64Var(&access.HotSetFraction, "hot-set-fraction", 0.01, "fraction of the keys in the hot set of the hotset access distribution")
+	cmd.Flags().Float64Var(&access.HotSetProbability, "hot-set-probability", 0.9, "probability that the hotset access distribution targets the hot set")
+	cmd.Flags().Float64Var(&access.RecencyMean, "recency-mean", 1000, "mean number of keys created after the keys targeted by the recent access distribution")
+	cmd.Flags().StringVar(&values.ValueMode, "value-mode", "",
+		fmt.Sprintf("if set, how the values of all stores are generated (%s|%s|%s|%s); default is random",
+			bench.ValueRandom, bench.ValueCompressible, bench.ValueProtobuf, bench.ValueCounter))
+	cmd.Flags().Float64Var(&values.ValueCompressibility, "value-compressibility", 0.5, "fraction of the bytes of compressible values which repeat earlier bytes, in [0, 1)")
+	cmd.Flags().Float64Var(&values.ValueReuse, "value-reuse", 0, "if set, the probability in all stores that an update writes the last value of the key with a few bytes changed")
+	cmd.Flags().IntVar(&values.ValueMutations, "value-mutations", 2, "number of bytes changed in reused values")
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		var gens []bench.StoreParams
-		switch profile {
-		case "mixed":
+		switch {
+		case storeParamsFile != "":
+			var err error
+			gens, err = readStoreParams(storeParamsFile, versions)
+			if err != nil {
+				return err
+			}
+		case profile == "mixed":
 			gens = MixedGenerators(versions, scale)
-		case "osmo":
+		case profile == "structured":
+			gens = StructuredGenerators(versions, scale)
+		case profile == "osmo":
 			gens = bench.OsmoLikeGenerators(scale)
 		default:
 			return fmt.Errorf("unknown generator profile: %s", profile)
 		}
+		if access.AccessDistribution != "" {
+			for i := range gens {
+				gens[i].AccessDistribution = access.AccessDistribution
+				gens[i].ZipfSkew = access.ZipfSkew
+				gens[i].HotSetFraction = access.HotSetFraction
+				gens[i].HotSetProbability = access.HotSetProbability
+				gens[i].RecencyMean = access.RecencyMean
+			}
+		}
+		if values.ValueMode != "" {
+			for i := range gens {
+				gens[i].ValueMode = values.ValueMode
+				gens[i].ValueCompressibility = values.ValueCompressibility
+			}
+		}
+		if values.ValueReuse != 0 {
+			for i := range gens {
+				gens[i].ValueReuse = values.ValueReuse
+				gens[i].ValueMutations = values.ValueMutations
+			}
+		}
 
 		outDir := args[0]
 
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// isolatedCommand returns the command executing the runner of plan with args, pinned to the cpus of the plan
+// and with its Go runtime settings.
+func isolatedCommand(plan RunPlan, args []string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if plan.CPUs != "" {
+		// taskset sets the affinity before executing the runner, so that it applies to all of its threads
+		taskset, err := exec.LookPath("taskset")
+		if err != nil {
+			return nil, fmt.Errorf("pinning cpus requires taskset: %w", err)
+		}
+		cmd = exec.Command(taskset, append([]string{"--cpu-list", plan.CPUs, plan.Runner}, args...)...)
+	} else {
+		cmd = exec.Command(plan.Runner, args...)
+	}
+
+	if env := runnerEnv(plan); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd, nil
+}
+
+// runnerEnv returns the environment variables set for the runner of plan in addition to those of the orchestrator.
+func runnerEnv(plan RunPlan) []string {
+	var env []string
+	if plan.GOMAXPROCS != 0 {
+		env = append(env, fmt.Sprintf("GOMAXPROCS=%d", plan.GOMAXPROCS))
+	}
+	if plan.GOMEMLIMIT != "" {
+		env = append(env, "GOMEMLIMIT="+plan.GOMEMLIMIT)
+	}
+	return env
+}
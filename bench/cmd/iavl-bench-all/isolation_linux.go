@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+func cgroupV2Available() bool {
+	_, err := os.Stat("/sys/fs/cgroup/cgroup.controllers")
+	return err == nil
+}
+
+// limitMemory creates a cgroup for the run under the cgroup parent with the memory limit of plan and
+// configures cmd to start in it. The returned function removes the cgroup after cmd has exited.
+func (o *orchestrator) limitMemory(cmd *exec.Cmd, plan RunPlan) (func(), error) {
+	err := os.MkdirAll(o.cgroupParent, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cgroup parent: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(o.cgroupParent, "cgroup.subtree_control"), []byte("+memory"), 0)
+	if err != nil {
+		return nil, fmt.Errorf("error enabling memory controller in %s: %w", o.cgroupParent, err)
+	}
+
+	dir := filepath.Join(o.cgroupParent, plan.RunName)
+	err = os.Mkdir(dir, 0755)
+	// the cgroup of a run which was interrupted is reused
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("error creating cgroup: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(plan.MemoryLimit), 0)
+	if err != nil {
+		_ = os.Remove(dir)
+		return nil, fmt.Errorf("error setting memory.max: %w", err)
+	}
+	// without swap the limit behaves like the memory of a smaller machine, swap may not be enabled at all
+	_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0)
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		_ = os.Remove(dir)
+		return nil, fmt.Errorf("error opening cgroup: %w", err)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: int(fd.Fd())}
+	return func() {
+		_ = fd.Close()
+		err := os.Remove(dir)
+		if err != nil {
+			o.logger.Warn("error removing cgroup", "dir", dir, "error", err)
+		}
+	}, nil
+}
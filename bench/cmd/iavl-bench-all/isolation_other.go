@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func cgroupV2Available() bool {
+	return false
+}
+
+func (o *orchestrator) limitMemory(cmd *exec.Cmd, plan RunPlan) (func(), error) {
+	return nil, fmt.Errorf("memory limits require cgroup v2")
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tidwall/jsonc"
+
+	"github.com/cosmos/iavl-bench/bench"
 )
 
 type Plan struct {
@@ -21,6 +28,32 @@ type RunPlan struct {
 	RunName string          `json:"name"`
 	Runner  string          `json:"runner"`
 	Options json.RawMessage `json:"options"`
+	// Matrix optionally maps option names to the values to run with, expanding the run into one run per combination.
+	Matrix map[string][]json.RawMessage `json:"matrix,omitempty"`
+	// Repeat is the number of trials of the run, overriding the repeat flag if set.
+	Repeat int `json:"repeat,omitempty"`
+	// CPUs optionally pins the runner to a cpu list in the format of taskset, e.g. "0-3,8".
+	CPUs string `json:"cpus,omitempty"`
+	// GOMAXPROCS optionally sets the GOMAXPROCS environment variable of the runner.
+	GOMAXPROCS int `json:"gomaxprocs,omitempty"`
+	// GOMEMLIMIT optionally sets the GOMEMLIMIT environment variable of the runner, e.g. "6GiB".
+	GOMEMLIMIT string `json:"gomemlimit,omitempty"`
+	// MemoryLimit optionally limits the memory of the runner, including the page cache, with a cgroup v2
+	// memory.max value in bytes, e.g. "8589934592".
+	MemoryLimit string `json:"memory_limit,omitempty"`
+}
+
+// orchestrator executes the runs of a plan.
+type orchestrator struct {
+	logger       *slog.Logger
+	changesetDir string
+	versions     int64
+	resultDir    string
+	dryRun       bool
+	// cgroupParent is the cgroup v2 dir under which runs with a memory limit get a cgroup of their own.
+	cgroupParent string
+	// manifest is nil in dry runs.
+	manifest *resultManifest
 }
 
 func main() {
@@ -28,6 +61,14 @@ func main() {
 	var changesetDir string
 	var versions int64
 	var outDir string
+	var verify bool
+	var resume bool
+	var profileInterval int64
+	var repeat int
+	var parallel int
+	var pinCPUs bool
+	var cgroupParent string
+	var onFailure string
 	cmd := &cobra.Command{
 		Use:   "bench-all [plan-file]",
 		Short: "Run all benchmarks in the given JSON/JSONC plan file.",
@@ -37,8 +78,28 @@ func main() {
 	cmd.Flags().StringVar(&changesetDir, "changeset-dir", "", "Directory containing changesets.")
 	cmd.Flags().Int64Var(&versions, "target-version", 0, "If non-zero, the target version to run the benchmarks against.")
 	cmd.Flags().StringVar(&outDir, "out-dir", "", "If set, the directory to write results to. Defaults to a timestamped directory next to the plan file.")
+	cmd.Flags().BoolVar(&verify, "verify", false, "If true, instead of benchmarking, replay the changesets through every run and check that all runs produce the same root hashes as the first one.")
+	cmd.Flags().BoolVar(&resume, "resume", false, "If true, continue the runs of a previous invocation in out-dir from their db dirs, skipping runs which already completed.")
+	cmd.Flags().Int64Var(&profileInterval, "profile-interval", 0, "If non-zero, each benchmark writes CPU, heap, mutex and block profiles covering this many versions to a profiles dir next to its log.")
+	cmd.Flags().IntVar(&repeat, "repeat", 1, "Number of trials of each run without a repeat of its own. Runs with more than one trial are aggregated into trials.json in the result dir.")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "Number of benchmark runs to execute concurrently.")
+	cmd.Flags().BoolVar(&pinCPUs, "pin-cpus", false, "If true, split the cpus evenly between the concurrent runs and pin each run to its share. Runs with cpus set in the plan keep them. Requires taskset.")
+	cmd.Flags().StringVar(&cgroupParent, "cgroup-parent", "/sys/fs/cgroup/iavl-bench", "Cgroup v2 dir which is created if needed to hold a cgroup for every run with a memory_limit.")
+	cmd.Flags().StringVar(&onFailure, "on-failure", FailureContinue, "What to do when a run fails. One of 'continue' to execute the remaining runs or 'abort' to not start any more runs. The exit status reflects failed runs either way.")
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		planFile := args[0]
+		if resume && outDir == "" {
+			return fmt.Errorf("resume requires out-dir to be set to the result dir of the previous invocation")
+		}
+		if resume && verify {
+			return fmt.Errorf("resume is not supported with verify")
+		}
+		if parallel < 1 {
+			return fmt.Errorf("parallel must be positive")
+		}
+		if onFailure != FailureContinue && onFailure != FailureAbort {
+			return fmt.Errorf("unknown failure policy %q, expected %s or %s", onFailure, FailureContinue, FailureAbort)
+		}
 		bz, err := os.ReadFile(planFile)
 		if err != nil {
 			return fmt.Errorf("error reading plan file: %w", err)
@@ -49,6 +110,10 @@ func main() {
 		if err != nil {
 			return fmt.Errorf("error unmarshaling plan file: %w", err)
 		}
+		plan.Runs, err = expandMatrix(plan.Runs)
+		if err != nil {
+			return err
+		}
 
 		logger := slog.Default()
 
@@ -68,63 +133,403 @@ func main() {
 			}
 		}
 
-		for _, run := range plan.Runs {
-			runOne(logger, run, changesetDir, versions, outDir, dryRun)
+		o := &orchestrator{
+			logger:       logger,
+			changesetDir: changesetDir,
+			versions:     versions,
+			resultDir:    outDir,
+			dryRun:       dryRun,
+			cgroupParent: cgroupParent,
+		}
+		if !dryRun {
+			o.manifest, err = newResultManifest(logger, planFile, plan, changesetDir, outDir, resume)
+			if err != nil {
+				return err
+			}
+			err = o.manifest.write()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err := o.manifest.finish()
+				if err != nil {
+					logger.Error("error writing result manifest", "error", err)
+				}
+			}()
 		}
 
+		if verify {
+			return o.verifyAll(plan)
+		}
+
+		runs, groups, err := expandTrials(plan.Runs, repeat)
+		if err != nil {
+			return err
+		}
+
+		var cpuSlots []string
+		if pinCPUs {
+			cpuSlots, err = splitCPUs(runtime.NumCPU(), min(parallel, len(runs)))
+			if err != nil {
+				return err
+			}
+		}
+
+		failed := o.runParallel(runs, parallel, onFailure, func(run RunPlan, slot int) error {
+			if run.CPUs == "" && cpuSlots != nil {
+				run.CPUs = cpuSlots[slot]
+			}
+			var extraArgs []string
+			if profileInterval != 0 {
+				extraArgs = append(extraArgs,
+					"--profile-dir", profileDir(outDir, run),
+					"--profile-interval", fmt.Sprintf("%d", profileInterval),
+				)
+			}
+			if resume {
+				return o.resumeOne(run, extraArgs)
+			}
+			return o.runOne(run, "bench", extraArgs)
+		})
+
+		if dryRun {
+			return nil
+		}
+		err = aggregateTrials(logger, groups, outDir)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			// usage is only useful for invalid arguments
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d runs failed", failed, len(runs))
+		}
 		return nil
 	}
 	if err := cmd.Execute(); err != nil {
-		panic(err)
+		os.Exit(1)
 	}
 }
 
-func runOne(logger *slog.Logger, plan RunPlan, changesetDir string, versions int64, resultDir string, dryRun bool) {
-	bz, err := json.Marshal(plan)
+func dbDir(resultDir string, plan RunPlan) string {
+	return filepath.Join(resultDir, fmt.Sprintf("%s-tmp", plan.RunName))
+}
+
+// logName is the base name of the files of a run of command.
+func logName(plan RunPlan, command string) string {
+	if command != "bench" {
+		return fmt.Sprintf("%s-%s", plan.RunName, command)
+	}
+	return plan.RunName
+}
+
+func logFilename(resultDir string, plan RunPlan, command string) string {
+	return filepath.Join(resultDir, fmt.Sprintf("%s.jsonl", logName(plan, command)))
+}
+
+func profileDir(resultDir string, plan RunPlan) string {
+	return filepath.Join(resultDir, fmt.Sprintf("%s.profiles", plan.RunName))
+}
+
+// Failure policies of the orchestrator.
+const (
+	// FailureContinue executes the remaining runs after a run failed.
+	FailureContinue = "continue"
+	// FailureAbort doesn't start any more runs after a run failed, runs which already started are completed.
+	FailureAbort = "abort"
+)
+
+// runParallel calls fn for every run from parallel goroutines, passing the index of the goroutine as the slot,
+// and returns the number of runs which failed. With FailureAbort no more runs are started after one failed.
+func (o *orchestrator) runParallel(runs []RunPlan, parallel int, onFailure string, fn func(run RunPlan, slot int) error) int {
+	ch := make(chan RunPlan)
+	var failed, skipped atomic.Int32
+	var wg sync.WaitGroup
+	for slot := 0; slot < min(parallel, len(runs)); slot++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range ch {
+				if onFailure == FailureAbort && failed.Load() > 0 {
+					skipped.Add(1)
+					continue
+				}
+				if fn(run, slot) != nil {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	for _, run := range runs {
+		ch <- run
+	}
+	close(ch)
+	wg.Wait()
+	if skipped.Load() > 0 {
+		o.logger.Error("aborted after failed run", "skipped_runs", skipped.Load())
+	}
+	return int(failed.Load())
+}
+
+// splitCPUs divides numCPU cpus into n contiguous cpu lists of equal size.
+func splitCPUs(numCPU, n int) ([]string, error) {
+	perSlot := numCPU / n
+	if perSlot == 0 {
+		return nil, fmt.Errorf("can't pin %d concurrent runs to %d cpus", n, numCPU)
+	}
+	res := make([]string, n)
+	for i := range res {
+		res[i] = fmt.Sprintf("%d-%d", i*perSlot, (i+1)*perSlot-1)
+	}
+	return res, nil
+}
+
+// resumeOne continues a bench run from its db dir and log file, unless its log shows that it already completed.
+func (o *orchestrator) resumeOne(plan RunPlan, extraArgs []string) error {
+	logger := o.logger
+	complete, err := runComplete(logFilename(o.resultDir, plan, "bench"))
 	if err != nil {
-		logger.Error("error marshaling plan", "error", err)
-		return
+		logger.Error("error reading run log", "run", plan.RunName, "error", err)
+		return err
+	}
+	if complete {
+		logger.Info("run already complete, skipping", "run", plan.RunName)
+		return nil
+	}
+	return o.runOne(plan, "bench", append([]string{"--resume"}, extraArgs...))
+}
+
+// runComplete returns whether the jsonl log of a run records its completion. A missing log means the run never started.
+func runComplete(logFile string) (bool, error) {
+	f, err := os.Open(logFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
 	}
-	logger.Info("starting run", "run_plan", string(bz))
-	dir := filepath.Join(resultDir, fmt.Sprintf("%s-tmp", plan.RunName))
-	err = os.Mkdir(dir, 0700)
 	if err != nil {
-		logger.Error("error creating db dir", "error", err)
-		return
+		return false, err
 	}
-	defer os.RemoveAll(dir)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Msg string `json:"msg"`
+		}
+		// lines which aren't log entries, e.g. a line cut off by a crash, are skipped
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Msg == "benchmark run complete" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// runOne executes the runner of plan with command, logging and returning an error if the run failed.
+func (o *orchestrator) runOne(plan RunPlan, command string, extraArgs []string) error {
+	logger := o.logger.With("run", plan.RunName)
+	err := o.execute(logger, plan, command, extraArgs)
+	if err != nil {
+		logger.Error("run failed", "command", command, "error", err)
+	}
+	return err
+}
+
+func (o *orchestrator) execute(logger *slog.Logger, plan RunPlan, command string, extraArgs []string) error {
+	bz, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("error marshaling plan: %w", err)
+	}
+	logger.Info("starting run", "run_plan", string(bz))
+	dir := dbDir(o.resultDir, plan)
+	resume := slices.Contains(extraArgs, "--resume")
 
 	args := []string{
-		"bench",
+		command,
 		"--changeset-dir",
-		changesetDir,
+		o.changesetDir,
 		"--db-dir",
 		dir,
 		"--log-type",
 		"json",
 		"--log-file",
-		filepath.Join(resultDir, fmt.Sprintf("%s.jsonl", plan.RunName)),
+		logFilename(o.resultDir, plan, command),
 	}
+	args = append(args, extraArgs...)
 
 	if plan.Options != nil {
 		args = append(args, "--db-options", string(plan.Options))
 	}
 
-	if versions != 0 {
-		args = append(args, "--target-version", fmt.Sprintf("%d", versions))
+	if o.versions != 0 {
+		args = append(args, "--target-version", fmt.Sprintf("%d", o.versions))
 	}
 
-	cmd := exec.Command(plan.Runner, args...)
+	cmd, err := isolatedCommand(plan, args)
+	if err != nil {
+		return fmt.Errorf("error isolating runner: %w", err)
+	}
 	logger.Info("executing runner command", "cmd", cmd.String())
-	if dryRun {
+	if o.dryRun {
 		logger.Info("dry run, not executing command")
-		return
+		return nil
 	}
 
-	out, err := cmd.CombinedOutput()
+	if resume {
+		err = os.MkdirAll(dir, 0700)
+	} else {
+		err = os.Mkdir(dir, 0700)
+	}
 	if err != nil {
-		logger.Error("error running benchmark", "error", err, "output", string(out))
-		return
+		return fmt.Errorf("error creating db dir: %w", err)
+	}
+
+	if plan.MemoryLimit != "" {
+		if cgroupV2Available() {
+			cleanup, err := o.limitMemory(cmd, plan)
+			if err != nil {
+				return fmt.Errorf("error limiting memory: %w", err)
+			}
+			defer cleanup()
+		} else {
+			logger.Warn("cgroup v2 is not available, running without memory limit", "memory_limit", plan.MemoryLimit)
+		}
+	}
+
+	// the output of a resumed run is appended to the output of the runs before it
+	outputFile := outputFilename(o.resultDir, plan, command)
+	output, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %w", err)
+	}
+	defer output.Close()
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	manifest := runManifest{
+		Run:        plan.RunName,
+		Command:    command,
+		Args:       cmd.Args,
+		Env:        runnerEnv(plan),
+		LogFile:    logFilename(o.resultDir, plan, command),
+		OutputFile: outputFile,
+		StartTime:  time.Now(),
+	}
+	runErr := cmd.Run()
+	manifest.finish(cmd, runErr)
+	err = manifest.write(manifestFilename(o.resultDir, plan, command))
+	if err != nil {
+		logger.Error("error writing run manifest", "error", err)
+	}
+	err = o.manifest.addRun(manifest)
+	if err != nil {
+		logger.Error("error writing result manifest", "error", err)
+	}
+
+	if runErr != nil && command == "bench" {
+		// the db dir is kept so that the run can be continued with --resume
+		return fmt.Errorf("error running benchmark, see %s, db dir %s is kept: %w", outputFile, dir, runErr)
+	}
+	err = os.RemoveAll(dir)
+	if err != nil {
+		logger.Error("error removing db dir", "error", err)
+	}
+	if runErr != nil {
+		return fmt.Errorf("error running %s, see %s: %w", command, outputFile, runErr)
+	}
+	logger.Info("done", "wall_time", manifest.WallTime, "peak_rss", manifest.PeakRSS)
+	return nil
+}
+
+func hashesFilename(resultDir string, plan RunPlan) string {
+	return filepath.Join(resultDir, fmt.Sprintf("%s.hashes.jsonl", plan.RunName))
+}
+
+// verifyAll runs the verify command of every runner in the plan, using the hashes of the first run as the
+// reference for all others, and reports the first divergent version and store of each run.
+func (o *orchestrator) verifyAll(plan Plan) error {
+	logger, resultDir := o.logger, o.resultDir
+	if len(plan.Runs) < 2 {
+		return fmt.Errorf("verify requires at least two runs in the plan, got %d", len(plan.Runs))
+	}
+
+	reference := plan.Runs[0]
+	referenceFile := hashesFilename(resultDir, reference)
+	err := o.runOne(reference, "verify", []string{"--hashes-file", referenceFile})
+	if err != nil {
+		return fmt.Errorf("reference run %s failed: %w", reference.RunName, err)
+	}
+	runErrs := map[string]error{}
+	for _, run := range plan.Runs[1:] {
+		args := []string{"--hashes-file", hashesFilename(resultDir, run), "--reference-hashes", referenceFile}
+		if err := o.runOne(run, "verify", args); err != nil {
+			runErrs[run.RunName] = err
+		}
+	}
+	if o.dryRun {
+		return nil
+	}
+
+	referenceHashes, err := bench.ReadHashLog(referenceFile)
+	if err != nil {
+		return fmt.Errorf("error reading reference hashes of %s: %w", reference.RunName, err)
+	}
+	if len(referenceHashes) == 0 {
+		return fmt.Errorf("reference run %s logged no hashes", reference.RunName)
+	}
+	// without a target version, the runs must reach the last version of the completed reference run
+	target := o.versions
+	if last := referenceHashes[len(referenceHashes)-1].Version; target == 0 {
+		target = last
+	} else if last < target {
+		return fmt.Errorf("reference run %s logged hashes up to version %d, before target version %d", reference.RunName, last, target)
+	}
+	failed := 0
+	for _, run := range plan.Runs[1:] {
+		if err := runErrs[run.RunName]; err != nil {
+			logger.Error("run failed, hashes not compared", "run", run.RunName, "error", err)
+			failed++
+			continue
+		}
+		hashes, err := bench.ReadHashLog(hashesFilename(resultDir, run))
+		if err != nil {
+			logger.Error("error reading hashes", "run", run.RunName, "error", err)
+			failed++
+			continue
+		}
+		compared, divergence := bench.CompareHashLogs(referenceHashes, hashes)
+		if divergence != nil {
+			logger.Error("hashes diverge",
+				"run", run.RunName,
+				"reference", reference.RunName,
+				"version", divergence.Version,
+				"store", divergence.Store,
+				"expected", divergence.Expected,
+				"actual", divergence.Actual,
+			)
+			failed++
+			continue
+		}
+		lastVersion := int64(0)
+		if len(hashes) > 0 {
+			lastVersion = hashes[len(hashes)-1].Version
+		}
+		if compared < len(referenceHashes) || lastVersion < target {
+			logger.Error("hashes match but run is incomplete",
+				"run", run.RunName,
+				"reference", reference.RunName,
+				"versions_compared", compared,
+				"reference_versions", len(referenceHashes),
+				"last_version", lastVersion,
+				"target_version", target,
+			)
+			failed++
+			continue
+		}
+		logger.Info("hashes match", "run", run.RunName, "reference", reference.RunName, "versions_compared", compared)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d runs failed verification against %s", failed, len(plan.Runs)-1, reference.RunName)
 	}
-	logger.Info("done")
+	return nil
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// runManifest is the machine-readable record of how a runner process was executed and how it ended,
+// written next to the log of the run.
+type runManifest struct {
+	Run     string   `json:"run"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Env are the environment variables set for the runner in addition to those of the orchestrator.
+	Env        []string  `json:"env,omitempty"`
+	LogFile    string    `json:"log_file"`
+	OutputFile string    `json:"output_file"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	// WallTime is in nanoseconds.
+	WallTime time.Duration `json:"wall_time"`
+	// ExitCode is -1 if the runner was killed by a signal or couldn't be started.
+	ExitCode int `json:"exit_code"`
+	// Signal is the name of the signal which killed the runner, if any.
+	Signal string `json:"signal,omitempty"`
+	// PeakRSS is the maximum resident set size of the runner in bytes, 0 where the platform doesn't report it.
+	PeakRSS uint64 `json:"peak_rss"`
+	Error   string `json:"error,omitempty"`
+}
+
+func manifestFilename(resultDir string, plan RunPlan, command string) string {
+	return filepath.Join(resultDir, logName(plan, command)+".manifest.json")
+}
+
+func outputFilename(resultDir string, plan RunPlan, command string) string {
+	return filepath.Join(resultDir, logName(plan, command)+".stderr")
+}
+
+// finish records the end of cmd, which returned runErr.
+func (m *runManifest) finish(cmd *exec.Cmd, runErr error) {
+	m.EndTime = time.Now()
+	m.WallTime = m.EndTime.Sub(m.StartTime)
+	m.ExitCode = -1
+	if runErr != nil {
+		m.Error = runErr.Error()
+	}
+	state := cmd.ProcessState
+	if state == nil {
+		return
+	}
+	m.ExitCode = state.ExitCode()
+	m.Signal = exitSignal(state)
+	m.PeakRSS = peakRSS(state)
+}
+
+func (m *runManifest) write(path string) error {
+	bz, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, bz, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing manifest %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expandMatrix replaces every run which declares a matrix with one run per combination of its option axes.
+// The options of each expanded run are the options of the declared run with one value of every axis set,
+// and its name is the declared name followed by the axis names and values in axis name order,
+// e.g. "iavl-v2-checkpoint_interval-100-eviction_depth-16".
+func expandMatrix(runs []RunPlan) ([]RunPlan, error) {
+	var res []RunPlan
+	names := map[string]bool{}
+	for _, run := range runs {
+		expanded, err := expandRun(run)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding matrix of run %s: %w", run.RunName, err)
+		}
+		for _, r := range expanded {
+			if names[r.RunName] {
+				return nil, fmt.Errorf("duplicate run name %s", r.RunName)
+			}
+			names[r.RunName] = true
+		}
+		res = append(res, expanded...)
+	}
+	return res, nil
+}
+
+func expandRun(run RunPlan) ([]RunPlan, error) {
+	if len(run.Matrix) == 0 {
+		return []RunPlan{run}, nil
+	}
+
+	base := map[string]json.RawMessage{}
+	if len(run.Options) != 0 {
+		err := json.Unmarshal(run.Options, &base)
+		if err != nil {
+			return nil, fmt.Errorf("options must be a JSON object to use a matrix: %w", err)
+		}
+	}
+
+	axes := make([]string, 0, len(run.Matrix))
+	for axis, values := range run.Matrix {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("axis %s has no values", axis)
+		}
+		axes = append(axes, axis)
+	}
+	sort.Strings(axes)
+
+	// the first axis varies slowest, like nested loops in axis order
+	combinations := [][]json.RawMessage{nil}
+	for _, axis := range axes {
+		var next [][]json.RawMessage
+		for _, combination := range combinations {
+			for _, value := range run.Matrix[axis] {
+				next = append(next, append(append([]json.RawMessage(nil), combination...), value))
+			}
+		}
+		combinations = next
+	}
+
+	res := make([]RunPlan, 0, len(combinations))
+	for _, combination := range combinations {
+		options := make(map[string]json.RawMessage, len(base)+len(axes))
+		for k, v := range base {
+			options[k] = v
+		}
+		nameParts := []string{run.RunName}
+		for i, axis := range axes {
+			options[axis] = combination[i]
+			value, err := nameValue(combination[i])
+			if err != nil {
+				return nil, fmt.Errorf("axis %s: %w", axis, err)
+			}
+			nameParts = append(nameParts, axis, value)
+		}
+		bz, err := json.Marshal(options)
+		if err != nil {
+			return nil, err
+		}
+		// the expanded run keeps every other field of the plan, e.g. its cpus and memory limits
+		expanded := run
+		expanded.Matrix = nil
+		expanded.RunName = strings.Join(nameParts, "-")
+		expanded.Options = bz
+		res = append(res, expanded)
+	}
+	return res, nil
+}
+
+// nameValue formats an axis value for use in a run name, which is also used in file names.
+func nameValue(value json.RawMessage) (string, error) {
+	var str string
+	if json.Unmarshal(value, &str) != nil {
+		var compact bytes.Buffer
+		err := json.Compact(&compact, value)
+		if err != nil {
+			return "", err
+		}
+		str = compact.String()
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, str), nil
+}
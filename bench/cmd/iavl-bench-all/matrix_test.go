@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func rawValues(values ...string) []json.RawMessage {
+	res := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		res[i] = json.RawMessage(v)
+	}
+	return res
+}
+
+func TestExpandMatrix(t *testing.T) {
+	runs := []RunPlan{
+		{
+			RunName: "v2",
+			Runner:  "bench-iavl-v2",
+			Options: json.RawMessage(`{"cache_size": 1000, "eviction_depth": 8, "name": "x"}`),
+			Matrix: map[string][]json.RawMessage{
+				// overrides the option of the same name
+				"eviction_depth":      rawValues("16", "32"),
+				"checkpoint_interval": rawValues("100", "200"),
+				"mode":                rawValues(`"fast/sync"`),
+			},
+			Repeat:      3,
+			CPUs:        "0-3",
+			GOMAXPROCS:  4,
+			GOMEMLIMIT:  "6GiB",
+			MemoryLimit: "8589934592",
+		},
+		// runs without a matrix are kept as they are
+		{
+			RunName: "mem",
+			Runner:  "bench-memiavl",
+			Options: json.RawMessage(`{"snapshot_interval": 10}`),
+			CPUs:    "4",
+		},
+		// a matrix without options starts from an empty object
+		{
+			RunName: "v1",
+			Runner:  "bench-iavl-v1",
+			Matrix: map[string][]json.RawMessage{
+				"skip_fast_storage_upgrade": rawValues("true", "false"),
+			},
+		},
+	}
+	v2 := func(name, options string) RunPlan {
+		return RunPlan{
+			RunName:     name,
+			Runner:      "bench-iavl-v2",
+			Options:     json.RawMessage(options),
+			Repeat:      3,
+			CPUs:        "0-3",
+			GOMAXPROCS:  4,
+			GOMEMLIMIT:  "6GiB",
+			MemoryLimit: "8589934592",
+		}
+	}
+	// axes in name order, the first one varying slowest
+	want := []RunPlan{
+		v2("v2-checkpoint_interval-100-eviction_depth-16-mode-fast_sync",
+			`{"cache_size":1000,"checkpoint_interval":100,"eviction_depth":16,"mode":"fast/sync","name":"x"}`),
+		v2("v2-checkpoint_interval-100-eviction_depth-32-mode-fast_sync",
+			`{"cache_size":1000,"checkpoint_interval":100,"eviction_depth":32,"mode":"fast/sync","name":"x"}`),
+		v2("v2-checkpoint_interval-200-eviction_depth-16-mode-fast_sync",
+			`{"cache_size":1000,"checkpoint_interval":200,"eviction_depth":16,"mode":"fast/sync","name":"x"}`),
+		v2("v2-checkpoint_interval-200-eviction_depth-32-mode-fast_sync",
+			`{"cache_size":1000,"checkpoint_interval":200,"eviction_depth":32,"mode":"fast/sync","name":"x"}`),
+		runs[1],
+		{RunName: "v1-skip_fast_storage_upgrade-true", Runner: "bench-iavl-v1", Options: json.RawMessage(`{"skip_fast_storage_upgrade":true}`)},
+		{RunName: "v1-skip_fast_storage_upgrade-false", Runner: "bench-iavl-v1", Options: json.RawMessage(`{"skip_fast_storage_upgrade":false}`)},
+	}
+
+	got, err := expandMatrix(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expanded %d runs, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.RunName != w.RunName {
+			t.Errorf("run %d: name %s, want %s", i, g.RunName, w.RunName)
+		}
+		if g.Runner != w.Runner {
+			t.Errorf("run %s: runner %s, want %s", w.RunName, g.Runner, w.Runner)
+		}
+		if string(g.Options) != string(w.Options) {
+			t.Errorf("run %s: options %s, want %s", w.RunName, g.Options, w.Options)
+		}
+		if g.Matrix != nil {
+			t.Errorf("run %s: matrix %v kept", w.RunName, g.Matrix)
+		}
+		if g.Repeat != w.Repeat {
+			t.Errorf("run %s: repeat %d, want %d", w.RunName, g.Repeat, w.Repeat)
+		}
+		if g.CPUs != w.CPUs {
+			t.Errorf("run %s: cpus %q, want %q", w.RunName, g.CPUs, w.CPUs)
+		}
+		if g.GOMAXPROCS != w.GOMAXPROCS {
+			t.Errorf("run %s: gomaxprocs %d, want %d", w.RunName, g.GOMAXPROCS, w.GOMAXPROCS)
+		}
+		if g.GOMEMLIMIT != w.GOMEMLIMIT {
+			t.Errorf("run %s: gomemlimit %q, want %q", w.RunName, g.GOMEMLIMIT, w.GOMEMLIMIT)
+		}
+		if g.MemoryLimit != w.MemoryLimit {
+			t.Errorf("run %s: memory_limit %q, want %q", w.RunName, g.MemoryLimit, w.MemoryLimit)
+		}
+	}
+	// catch fields added to RunPlan without a check above
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandMatrix() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExpandMatrixErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		runs []RunPlan
+	}{
+		{
+			name: "options not an object",
+			runs: []RunPlan{{
+				RunName: "a",
+				Options: json.RawMessage(`[1]`),
+				Matrix:  map[string][]json.RawMessage{"x": rawValues("1")},
+			}},
+		},
+		{
+			name: "axis without values",
+			runs: []RunPlan{{
+				RunName: "a",
+				Matrix:  map[string][]json.RawMessage{"x": nil},
+			}},
+		},
+		{
+			name: "values with the same name",
+			runs: []RunPlan{{
+				RunName: "a",
+				Matrix:  map[string][]json.RawMessage{"x": rawValues(`"a/b"`, `"a_b"`)},
+			}},
+		},
+		{
+			name: "expanded name of another run",
+			runs: []RunPlan{
+				{RunName: "a-x-1"},
+				{RunName: "a", Matrix: map[string][]json.RawMessage{"x": rawValues("1", "2")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := expandMatrix(tt.runs); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNameValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: `100`, want: "100"},
+		{value: `1.5`, want: "1.5"},
+		{value: `true`, want: "true"},
+		{value: `"fast-sync_v2"`, want: "fast-sync_v2"},
+		{value: `"a/b c"`, want: "a_b_c"},
+		{value: `{"a": [1, 2]}`, want: `__a___1_2__`},
+	}
+	for _, tt := range tests {
+		got, err := nameValue(json.RawMessage(tt.value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("nameValue(%s) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// exitSignal returns the name of the signal which killed the process, or "" if it exited.
+func exitSignal(state *os.ProcessState) string {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	return status.Signal().String()
+}
+
+// peakRSS returns the maximum resident set size of the process in bytes.
+func peakRSS(state *os.ProcessState) uint64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// linux reports kilobytes
+	return uint64(usage.Maxrss) * 1024
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+func exitSignal(state *os.ProcessState) string {
+	return ""
+}
+
+func peakRSS(state *os.ProcessState) uint64 {
+	return 0
+}
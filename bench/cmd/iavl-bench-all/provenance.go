@@ -0,0 +1,358 @@
+package main
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/tidwall/jsonc"
+)
+
+// resultManifest records everything needed to tell how the results in a result dir were produced:
+// the plan, the exact code of every runner, the changesets, the host and how each run ended.
+// It is written to manifest.json in the result dir and rewritten after every run.
+type resultManifest struct {
+	PlanFile string          `json:"plan_file"`
+	Plan     json.RawMessage `json:"plan"`
+	// Args are the command line arguments of the orchestrator.
+	Args []string `json:"args"`
+	// IavlBench is the build of the orchestrator, whose vcs settings identify the iavl-bench commit.
+	IavlBench *binaryInfo `json:"iavl_bench,omitempty"`
+	// IavlBenchGit identifies the iavl-bench commit from the git checkout holding the working dir or the plan file
+	// if the build of the orchestrator has no vcs settings, e.g. with go run or go install of a module version.
+	IavlBenchGit  *gitInfo        `json:"iavl_bench_git,omitempty"`
+	Runners       []binaryInfo    `json:"runners"`
+	ChangesetDir  string          `json:"changeset_dir,omitempty"`
+	ChangesetInfo json.RawMessage `json:"changeset_info,omitempty"`
+	Host          hostInfo        `json:"host"`
+	StartTime     time.Time       `json:"start_time"`
+	// EndTime is unset while runs are executing.
+	EndTime *time.Time `json:"end_time,omitempty"`
+	// Runs are the manifests of the executed runs in order of completion. A resumed result dir keeps
+	// the runs of the previous invocations, so a run which was resumed appears more than once.
+	Runs []runManifest `json:"runs"`
+
+	mtx  sync.Mutex
+	path string
+}
+
+// binaryInfo describes a Go binary by its content hash and the module versions it was built from.
+type binaryInfo struct {
+	// Runner is the runner as given in the plan, empty for the orchestrator.
+	Runner    string       `json:"runner,omitempty"`
+	Path      string       `json:"path"`
+	SHA256    string       `json:"sha256,omitempty"`
+	GoVersion string       `json:"go_version,omitempty"`
+	Package   string       `json:"package,omitempty"`
+	Main      *moduleInfo  `json:"main,omitempty"`
+	Deps      []moduleInfo `json:"deps,omitempty"`
+	// Settings are the build settings, including vcs.revision and vcs.modified if built from a git checkout.
+	Settings map[string]string `json:"settings,omitempty"`
+	// Error is set if the binary couldn't be resolved or its build info couldn't be read.
+	Error string `json:"error,omitempty"`
+}
+
+type gitInfo struct {
+	Dir      string `json:"dir"`
+	Revision string `json:"revision"`
+	// Dirty is true if the checkout has uncommitted changes.
+	Dirty bool `json:"dirty"`
+}
+
+type moduleInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+	// Replace is the module which replaced this one in the build, e.g. a fork or a local directory.
+	Replace *moduleInfo `json:"replace,omitempty"`
+}
+
+type hostInfo struct {
+	Hostname        string `json:"hostname,omitempty"`
+	OS              string `json:"os"`
+	Arch            string `json:"arch"`
+	Platform        string `json:"platform,omitempty"`
+	PlatformVersion string `json:"platform_version,omitempty"`
+	KernelVersion   string `json:"kernel_version,omitempty"`
+	Virtualization  string `json:"virtualization,omitempty"`
+	CPUModel        string `json:"cpu_model,omitempty"`
+	CPUCores        int    `json:"cpu_cores,omitempty"`
+	CPUThreads      int    `json:"cpu_threads"`
+	MemTotal        uint64 `json:"mem_total,omitempty"`
+	// ResultDirFstype and ResultDirDiskTotal describe the file system holding the result and db dirs.
+	ResultDirFstype    string `json:"result_dir_fstype,omitempty"`
+	ResultDirDiskTotal uint64 `json:"result_dir_disk_total,omitempty"`
+}
+
+func resultManifestFilename(resultDir string) string {
+	return filepath.Join(resultDir, "manifest.json")
+}
+
+// newResultManifest collects the provenance of the runs of plan. Information which can't be read is
+// logged and left out rather than failing the runs. With resume, the runs recorded in an existing
+// manifest in the result dir are kept.
+func newResultManifest(logger *slog.Logger, planFile string, plan Plan, changesetDir, resultDir string, resume bool) (*resultManifest, error) {
+	m := &resultManifest{
+		PlanFile:     planFile,
+		Args:         os.Args,
+		ChangesetDir: changesetDir,
+		StartTime:    time.Now(),
+		Runs:         []runManifest{},
+		path:         resultManifestFilename(resultDir),
+	}
+	if abs, err := filepath.Abs(planFile); err == nil {
+		m.PlanFile = abs
+	}
+	bz, err := os.ReadFile(planFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %w", err)
+	}
+	m.Plan = jsonc.ToJSON(bz)
+
+	if self, err := os.Executable(); err == nil {
+		info := readBinaryInfo(self)
+		m.IavlBench = &info
+	} else {
+		logger.Warn("could not resolve own executable", "error", err)
+	}
+	if m.IavlBench == nil || m.IavlBench.Settings["vcs.revision"] == "" {
+		m.IavlBenchGit = findIavlBenchGit(logger, filepath.Dir(m.PlanFile))
+	}
+	seen := map[string]bool{}
+	for _, run := range plan.Runs {
+		if seen[run.Runner] {
+			continue
+		}
+		seen[run.Runner] = true
+		info := binaryInfo{Runner: run.Runner}
+		path, err := exec.LookPath(run.Runner)
+		if err == nil {
+			path, err = filepath.Abs(path)
+		}
+		if err == nil {
+			path, err = filepath.EvalSymlinks(path)
+		}
+		if err != nil {
+			info.Error = err.Error()
+			logger.Warn("could not resolve runner", "runner", run.Runner, "error", err)
+		} else {
+			info = readBinaryInfo(path)
+			info.Runner = run.Runner
+		}
+		m.Runners = append(m.Runners, info)
+	}
+
+	if changesetDir != "" {
+		bz, err := os.ReadFile(filepath.Join(changesetDir, "changeset_info.json"))
+		if err != nil {
+			logger.Warn("could not read changeset info", "error", err)
+		} else {
+			m.ChangesetInfo = bz
+		}
+	}
+
+	m.Host = readHostInfo(logger, resultDir)
+
+	if resume {
+		bz, err := os.ReadFile(m.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("error reading manifest of previous invocation: %w", err)
+		default:
+			var previous resultManifest
+			err = json.Unmarshal(bz, &previous)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshaling manifest of previous invocation: %w", err)
+			}
+			m.StartTime = previous.StartTime
+			m.Runs = append(m.Runs, previous.Runs...)
+		}
+	}
+	return m, nil
+}
+
+func readBinaryInfo(path string) binaryInfo {
+	res := binaryInfo{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	// not runtime/debug.ReadBuildInfo, which only reads the build info of the running binary
+	info, err := buildinfo.Read(f)
+	if err != nil {
+		res.Error = fmt.Sprintf("error reading build info: %v", err)
+		return res
+	}
+	res.GoVersion = info.GoVersion
+	res.Package = info.Path
+	res.Main = newModuleInfo(&info.Main)
+	for _, dep := range info.Deps {
+		res.Deps = append(res.Deps, *newModuleInfo(dep))
+	}
+	if len(info.Settings) != 0 {
+		res.Settings = make(map[string]string, len(info.Settings))
+		for _, s := range info.Settings {
+			res.Settings[s.Key] = s.Value
+		}
+	}
+	return res
+}
+
+// findIavlBenchGit returns the iavl-bench git checkout holding the working dir or else planDir, or nil if neither
+// is in one.
+func findIavlBenchGit(logger *slog.Logger, planDir string) *gitInfo {
+	dirs := []string{planDir}
+	if wd, err := os.Getwd(); err == nil {
+		dirs = []string{wd, planDir}
+	}
+	for _, dir := range dirs {
+		bz, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+		if err != nil {
+			continue
+		}
+		root := strings.TrimSpace(string(bz))
+		// only a checkout of iavl-bench identifies the orchestrator
+		if _, err := os.Stat(filepath.Join(root, "bench", "cmd", "iavl-bench-all")); err != nil {
+			continue
+		}
+		bz, err = exec.Command("git", "-C", root, "rev-parse", "HEAD").Output()
+		if err != nil {
+			logger.Warn("could not read iavl-bench commit", "dir", root, "error", err)
+			return nil
+		}
+		info := &gitInfo{Dir: root, Revision: strings.TrimSpace(string(bz))}
+		bz, err = exec.Command("git", "-C", root, "status", "--porcelain").Output()
+		if err != nil {
+			logger.Warn("could not read iavl-bench git status", "dir", root, "error", err)
+		} else {
+			info.Dirty = strings.TrimSpace(string(bz)) != ""
+		}
+		return info
+	}
+	logger.Warn("could not find the iavl-bench commit, the orchestrator has no vcs build info and is not run from a checkout")
+	return nil
+}
+
+func newModuleInfo(m *debug.Module) *moduleInfo {
+	if m == nil {
+		return nil
+	}
+	return &moduleInfo{
+		Path:    m.Path,
+		Version: m.Version,
+		Sum:     m.Sum,
+		Replace: newModuleInfo(m.Replace),
+	}
+}
+
+func readHostInfo(logger *slog.Logger, resultDir string) hostInfo {
+	res := hostInfo{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUThreads: runtime.NumCPU(),
+	}
+	if info, err := host.Info(); err != nil {
+		logger.Warn("could not read host info", "error", err)
+	} else {
+		res.Hostname = info.Hostname
+		res.Platform = info.Platform
+		res.PlatformVersion = info.PlatformVersion
+		res.KernelVersion = info.KernelVersion
+		res.Virtualization = info.VirtualizationSystem
+	}
+	if infos, err := cpu.Info(); err != nil || len(infos) == 0 {
+		logger.Warn("could not read cpu info", "error", err)
+	} else {
+		res.CPUModel = infos[0].ModelName
+	}
+	if cores, err := cpu.Counts(false); err != nil {
+		logger.Warn("could not read cpu cores", "error", err)
+	} else {
+		res.CPUCores = cores
+	}
+	if info, err := mem.VirtualMemory(); err != nil {
+		logger.Warn("could not read memory info", "error", err)
+	} else {
+		res.MemTotal = info.Total
+	}
+	if usage, err := disk.Usage(resultDir); err != nil {
+		logger.Warn("could not read disk info", "error", err)
+	} else {
+		res.ResultDirFstype = usage.Fstype
+		res.ResultDirDiskTotal = usage.Total
+	}
+	return res
+}
+
+// addRun records a run which ended and rewrites the manifest. It is safe for concurrent use and a no-op on a nil manifest.
+func (m *resultManifest) addRun(run runManifest) error {
+	if m == nil {
+		return nil
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.Runs = append(m.Runs, run)
+	return m.writeLocked()
+}
+
+// write rewrites the manifest.
+func (m *resultManifest) write() error {
+	if m == nil {
+		return nil
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.writeLocked()
+}
+
+// finish records the end of the invocation and rewrites the manifest.
+func (m *resultManifest) finish() error {
+	if m == nil {
+		return nil
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	end := time.Now()
+	m.EndTime = &end
+	return m.writeLocked()
+}
+
+func (m *resultManifest) writeLocked() error {
+	bz, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(m.path, bz, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing manifest %s: %w", filepath.Base(m.path), err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos/iavl-bench/bench"
+)
+
+// trialGroup is a run of the plan which is repeated, each trial being a separate run with its own db dir and log.
+type trialGroup struct {
+	name   string
+	trials []RunPlan
+}
+
+// expandTrials replaces every run with repeat > 1 with one run per trial named "<name>-trial-<i>".
+// Runs without a repeat of their own use defaultRepeat. The trials are interleaved, i.e. the first trial of
+// every run is executed before the second trial of any run, so that drift of the machine over time, e.g.
+// from other load or thermal throttling, is spread across all runs instead of biasing some of them.
+// A trial name which is also the name of another run of the plan is an error.
+func expandTrials(runs []RunPlan, defaultRepeat int) ([]RunPlan, []trialGroup, error) {
+	var groups []trialGroup
+	maxRepeat := 1
+	repeats := make([]int, len(runs))
+	for i, run := range runs {
+		repeat := run.Repeat
+		if repeat == 0 {
+			repeat = defaultRepeat
+		}
+		if repeat < 1 {
+			return nil, nil, fmt.Errorf("repeat of run %s must be positive", run.RunName)
+		}
+		repeats[i] = repeat
+		maxRepeat = max(maxRepeat, repeat)
+		if repeat > 1 {
+			groups = append(groups, trialGroup{name: run.RunName})
+		}
+	}
+
+	var res []RunPlan
+	names := map[string]bool{}
+	add := func(run RunPlan) error {
+		if names[run.RunName] {
+			return fmt.Errorf("duplicate run name %s", run.RunName)
+		}
+		names[run.RunName] = true
+		res = append(res, run)
+		return nil
+	}
+	for trial := 1; trial <= maxRepeat; trial++ {
+		group := 0
+		for i, run := range runs {
+			if repeats[i] == 1 {
+				if trial == 1 {
+					err := add(run)
+					if err != nil {
+						return nil, nil, err
+					}
+				}
+				continue
+			}
+			if trial <= repeats[i] {
+				run.RunName = fmt.Sprintf("%s-trial-%d", run.RunName, trial)
+				err := add(run)
+				if err != nil {
+					return nil, nil, err
+				}
+				groups[group].trials = append(groups[group].trials, run)
+			}
+			group++
+		}
+	}
+	return res, groups, nil
+}
+
+// trialStats are the statistics of a metric across the complete trials of a run.
+type trialStats struct {
+	Mean   float64 `json:"mean"`
+	Stddev float64 `json:"stddev"`
+	// CILow and CIHigh bound the 95% confidence interval of the mean, assuming normally distributed trials.
+	CILow  float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+}
+
+// tQuantiles are the 0.975 quantiles of Student's t-distribution by degrees of freedom.
+var tQuantiles = []float64{
+	math.NaN(), 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+func newTrialStats(values []float64) trialStats {
+	n := float64(len(values))
+	var s trialStats
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= n
+	if len(values) < 2 {
+		s.CILow, s.CIHigh = s.Mean, s.Mean
+		return s
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (v - s.Mean) * (v - s.Mean)
+	}
+	s.Stddev = math.Sqrt(squares / (n - 1))
+	t := 1.96
+	if df := len(values) - 1; df < len(tQuantiles) {
+		t = tQuantiles[df]
+	}
+	halfWidth := t * s.Stddev / math.Sqrt(n)
+	s.CILow, s.CIHigh = s.Mean-halfWidth, s.Mean+halfWidth
+	return s
+}
+
+// trialSummary aggregates the summaries of the complete trials of a run.
+type trialSummary struct {
+	Name     string `json:"name"`
+	Trials   int    `json:"trials"`
+	Complete int    `json:"complete"`
+	// the statistics are omitted if no trial completed
+	OpsPerSec *trialStats `json:"ops_per_sec,omitempty"`
+	MaxMem    *trialStats `json:"max_mem,omitempty"`
+	MaxRSS    *trialStats `json:"max_rss,omitempty"`
+}
+
+func trialsFilename(resultDir string) string {
+	return filepath.Join(resultDir, "trials.json")
+}
+
+// aggregateTrials summarizes the logs of the trials of every group, logging the statistics of each group
+// and writing all of them to trials.json in the result dir.
+func aggregateTrials(logger *slog.Logger, groups []trialGroup, resultDir string) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	res := make([]trialSummary, 0, len(groups))
+	for _, group := range groups {
+		summary := trialSummary{Name: group.name, Trials: len(group.trials)}
+		var opsPerSec, maxMem, maxRSS []float64
+		for _, trial := range group.trials {
+			s, err := bench.SummarizeRunLog(logFilename(resultDir, trial, "bench"))
+			if err != nil {
+				logger.Error("error summarizing trial", "run", trial.RunName, "error", err)
+				continue
+			}
+			if !s.Complete {
+				continue
+			}
+			summary.Complete++
+			opsPerSec = append(opsPerSec, s.OpsPerSec)
+			maxMem = append(maxMem, float64(s.MaxMem))
+			maxRSS = append(maxRSS, float64(s.MaxRSS))
+		}
+		if summary.Complete > 0 {
+			ops, mem, rss := newTrialStats(opsPerSec), newTrialStats(maxMem), newTrialStats(maxRSS)
+			summary.OpsPerSec, summary.MaxMem, summary.MaxRSS = &ops, &mem, &rss
+		}
+		logger.Info("trial statistics",
+			"run", summary.Name,
+			"trials", summary.Trials,
+			"complete", summary.Complete,
+			"ops_per_sec", summary.OpsPerSec,
+			"max_mem", summary.MaxMem,
+			"max_rss", summary.MaxRSS,
+		)
+		res = append(res, summary)
+	}
+
+	bz, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(trialsFilename(resultDir), bz, 0644)
+}
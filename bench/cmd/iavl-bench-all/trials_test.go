@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestExpandTrials(t *testing.T) {
+	runs := []RunPlan{
+		{RunName: "a"},
+		{RunName: "b", Repeat: 1},
+		{RunName: "c", Repeat: 3},
+	}
+	got, groups, err := expandTrials(runs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, run := range got {
+		names = append(names, run.RunName)
+	}
+	// interleaved by trial
+	wantNames := []string{"a-trial-1", "b", "c-trial-1", "a-trial-2", "c-trial-2", "c-trial-3"}
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("expanded runs %v, want %v", names, wantNames)
+	}
+	if len(groups) != 2 || groups[0].name != "a" || len(groups[0].trials) != 2 || groups[1].name != "c" || len(groups[1].trials) != 3 {
+		t.Errorf("trial groups = %+v, want a with 2 trials and c with 3", groups)
+	}
+}
+
+func TestExpandTrialsErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		runs          []RunPlan
+		defaultRepeat int
+	}{
+		{
+			name:          "trial name of a later run",
+			runs:          []RunPlan{{RunName: "a", Repeat: 2}, {RunName: "a-trial-2"}},
+			defaultRepeat: 1,
+		},
+		{
+			name:          "trial name of an earlier run",
+			runs:          []RunPlan{{RunName: "a-trial-1", Repeat: 1}, {RunName: "a"}},
+			defaultRepeat: 3,
+		},
+		{
+			name:          "zero default repeat",
+			runs:          []RunPlan{{RunName: "a"}},
+			defaultRepeat: 0,
+		},
+		{
+			name:          "negative repeat",
+			runs:          []RunPlan{{RunName: "a", Repeat: -1}},
+			defaultRepeat: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := expandTrials(tt.runs, tt.defaultRepeat); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewTrialStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   trialStats
+	}{
+		{
+			name:   "single trial",
+			values: []float64{42},
+			want:   trialStats{Mean: 42, CILow: 42, CIHigh: 42},
+		},
+		{
+			name:   "identical trials",
+			values: []float64{7, 7, 7},
+			want:   trialStats{Mean: 7, CILow: 7, CIHigh: 7},
+		},
+		{
+			// t = 12.706 with 1 degree of freedom, half width 12.706 * sqrt(50) / sqrt(2)
+			name:   "two trials",
+			values: []float64{10, 20},
+			want:   trialStats{Mean: 15, Stddev: math.Sqrt(50), CILow: 15 - 63.53, CIHigh: 15 + 63.53},
+		},
+		{
+			// t = 2.776 with 4 degrees of freedom, half width 2.776 * sqrt(2.5) / sqrt(5)
+			name:   "five trials",
+			values: []float64{3, 1, 5, 2, 4},
+			want:   trialStats{Mean: 3, Stddev: math.Sqrt(2.5), CILow: 3 - 1.9629284245738559, CIHigh: 3 + 1.9629284245738559},
+		},
+		{
+			// beyond the table the normal quantile 1.96 is used, half width 1.96 * sqrt(32 / 31) / sqrt(32)
+			name:   "32 trials",
+			values: slices.Repeat([]float64{0, 2}, 16),
+			want:   trialStats{Mean: 1, Stddev: math.Sqrt(32.0 / 31), CILow: 1 - 0.35202639197247876, CIHigh: 1 + 0.35202639197247876},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTrialStats(tt.values)
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", got.Mean, tt.want.Mean},
+				{"stddev", got.Stddev, tt.want.Stddev},
+				{"ci_low", got.CILow, tt.want.CILow},
+				{"ci_high", got.CIHigh, tt.want.CIHigh},
+			} {
+				if math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %g, want %g", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/iavl-bench/bench"
+)
+
+func main() {
+	var format string
+	var thresholdFlags map[string]string
+	cmd := &cobra.Command{
+		Use:   "compare [baseline-dir] [candidate-dir]",
+		Short: "Compare the runs in a candidate result dir with the runs of the same name in a baseline result dir, failing if any metric regresses beyond its threshold.",
+		Args:  cobra.ExactArgs(2),
+	}
+	cmd.Flags().StringVar(&format, "format", "markdown", "Output format. One of 'markdown' or 'json'.")
+	cmd.Flags().StringToStringVar(&thresholdFlags, "thresholds",
+		map[string]string{"ops_per_sec": "0.05", "max_mem": "0.05", "max_disk": "0.05", "commit_p99": "0.05"},
+		fmt.Sprintf("Metrics to compare with the largest relative change in the worse direction which is not a regression, applied to all runs. Metrics are any of %s.",
+			strings.Join(bench.SummaryMetrics(), ", ")))
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		thresholds := make(map[string]float64, len(thresholdFlags))
+		for name, value := range thresholdFlags {
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("error parsing threshold of %s: %w", name, err)
+			}
+			thresholds[name] = threshold
+		}
+
+		baseline, err := bench.SummarizeRuns(args[0])
+		if err != nil {
+			return fmt.Errorf("error reading baseline: %w", err)
+		}
+		candidate, err := bench.SummarizeRuns(args[1])
+		if err != nil {
+			return fmt.Errorf("error reading candidate: %w", err)
+		}
+		comparisons, err := bench.CompareRuns(baseline, candidate, thresholds)
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case "markdown":
+			err = writeMarkdown(os.Stdout, comparisons)
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(comparisons)
+		default:
+			return fmt.Errorf("unknown format: %s", format)
+		}
+		if err != nil {
+			return err
+		}
+
+		regressed := 0
+		for _, c := range comparisons {
+			if c.Regressed() {
+				regressed++
+			}
+		}
+		if regressed > 0 {
+			// usage is only useful for invalid arguments
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d runs regressed", regressed, len(comparisons))
+		}
+		return nil
+	}
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func writeMarkdown(w io.Writer, comparisons []bench.RunComparison) error {
+	lines := []string{
+		"| run | metric | baseline | candidate | change | threshold | result |",
+		"| --- | --- | --- | --- | --- | --- | --- |",
+	}
+	for _, c := range comparisons {
+		if c.Failure != "" {
+			lines = append(lines, fmt.Sprintf("| %s | | | | | | FAIL: %s |", c.Run, c.Failure))
+			continue
+		}
+		for _, m := range c.Metrics {
+			result := "ok"
+			change := fmt.Sprintf("%+.1f%%", m.Change*100)
+			switch {
+			case m.Skipped:
+				result = "skipped"
+				change = ""
+			case m.Regressed:
+				result = "FAIL"
+			}
+			lines = append(lines, fmt.Sprintf("| %s | %s | %.6g | %.6g | %s | %.1f%% | %s |",
+				m.Run, m.Metric, m.Baseline, m.Candidate, change, m.Threshold*100, result))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/iavl-bench/bench"
+)
+
+func main() {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "report [run-dir-or-log...]",
+		Short: "Summarize and compare the jsonl logs of benchmark runs, e.g. a result dir of iavl-bench-all.",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	cmd.Flags().StringVar(&format, "format", "markdown", "Output format. One of 'markdown', 'csv' or 'json'.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		summaries, err := bench.SummarizeRuns(args...)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "markdown":
+			return writeMarkdown(os.Stdout, summaries)
+		case "csv":
+			return writeCSV(os.Stdout, summaries)
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(summaries)
+		default:
+			return fmt.Errorf("unknown format: %s", format)
+		}
+	}
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+var columns = []string{
+	"name",
+	"complete",
+	"versions_applied",
+	"elapsed_time_minutes",
+	"ops_per_sec",
+	"max_mem_gb",
+	"max_rss_gb",
+	"max_disk_gb",
+	"commit_p50_ms",
+	"commit_p90_ms",
+	"commit_p99_ms",
+	"commit_max_ms",
+}
+
+// row formats a summary in the units used by analysis/analysis.py, with sizes in GB.
+func row(s bench.RunSummary) []string {
+	gb := func(bytes uint64) string {
+		return fmt.Sprintf("%.3f", float64(bytes)/1_000_000_000)
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1f", float64(d)/float64(time.Millisecond))
+	}
+	commit := []string{"", "", "", ""}
+	if s.CommitLatency != nil {
+		commit = []string{ms(s.CommitLatency.P50), ms(s.CommitLatency.P90), ms(s.CommitLatency.P99), ms(s.CommitLatency.Max)}
+	}
+	return append([]string{
+		s.Name,
+		fmt.Sprintf("%t", s.Complete),
+		fmt.Sprintf("%d", s.VersionsApplied),
+		fmt.Sprintf("%.1f", s.Elapsed.Minutes()),
+		fmt.Sprintf("%.1f", s.OpsPerSec),
+		gb(s.MaxMem),
+		gb(s.MaxRSS),
+		gb(s.MaxDisk),
+	}, commit...)
+}
+
+func writeMarkdown(w io.Writer, summaries []bench.RunSummary) error {
+	lines := []string{
+		"| " + strings.Join(columns, " | ") + " |",
+		"|" + strings.Repeat(" --- |", len(columns)),
+	}
+	for _, s := range summaries {
+		lines = append(lines, "| "+strings.Join(row(s), " | ")+" |")
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func writeCSV(w io.Writer, summaries []bench.RunSummary) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(columns)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		err = writer.Write(row(s))
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
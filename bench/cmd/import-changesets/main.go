@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/iavl-bench/bench"
+)
+
+func main() {
+	var params bench.ImportParams
+	cmd := &cobra.Command{
+		Use:   "import-changesets [input-dir] [out-dir]",
+		Short: "Import the state changes recorded by a chain as changesets for iavl-bench",
+		Long: `Import the state changes recorded by a chain as changesets for iavl-bench.
+
+The input dir holds one file per block, either the output of the ADR-038 file streaming service of the
+Cosmos SDK v0.47 (format adr038) or files of varint length-delimited cosmos.store.v1beta1.StoreKVPair
+messages named by block height (format delimited). The first imported block becomes version 1. Unless it
+is the first block of the chain, the runs start without the state from before it.
+
+To export the changes stored in the application.db of a node instead, use the export-changesets command
+of the iavl-v1 runner.`,
+		Args: cobra.ExactArgs(2),
+	}
+	cmd.Flags().StringVar(&params.Format, "format", bench.ImportFormatADR038,
+		fmt.Sprintf("format of the input dir (%s|%s)", bench.ImportFormatADR038, bench.ImportFormatDelimited))
+	cmd.Flags().Int64Var(&params.StartHeight, "start-height", 0, "if non-zero, the first block to import")
+	cmd.Flags().Int64Var(&params.EndHeight, "end-height", 0, "if non-zero, the last block to import")
+	cmd.Flags().StringSliceVar(&params.Stores, "stores", nil, "if set, only import the changes of these stores")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if params.EndHeight != 0 && params.EndHeight < params.StartHeight {
+			return fmt.Errorf("end height %d is before start height %d", params.EndHeight, params.StartHeight)
+		}
+		params.InputDir = args[0]
+		return bench.ImportChangesets(params, args[1])
+	}
+	if err := cmd.Execute(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// CommitInfo describes the state produced by a call to Tree.Commit.
+type CommitInfo struct {
+	// StoreHashes are the root hashes of each store at the committed version, keyed by store name.
+	StoreHashes map[string][]byte
+	// Hash is the aggregate hash of all StoreHashes.
+	Hash []byte
+	// StoreCommitDurations optionally report the time each store took to commit, keyed by store name.
+	// It is nil for implementations which commit all stores at once.
+	StoreCommitDurations map[string]time.Duration
+}
+
+// NewCommitInfo builds a CommitInfo from per-store root hashes, computing the aggregate hash.
+// The aggregate hash is computed by the benchmark itself rather than by each implementation
+// so that it is comparable across implementations which combine store hashes differently.
+func NewCommitInfo(storeHashes map[string][]byte) CommitInfo {
+	return CommitInfo{
+		StoreHashes: storeHashes,
+		Hash:        aggregateHash(storeHashes),
+	}
+}
+
+// aggregateHash hashes the length-prefixed store names and hashes in store name order.
+func aggregateHash(storeHashes map[string][]byte) []byte {
+	names := make([]string, 0, len(storeHashes))
+	for name := range storeHashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, name := range names {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(name)))
+		h.Write(lenBuf[:n])
+		h.Write([]byte(name))
+		hash := storeHashes[name]
+		n = binary.PutUvarint(lenBuf[:], uint64(len(hash)))
+		h.Write(lenBuf[:n])
+		h.Write(hash)
+	}
+	return h.Sum(nil)
+}
+
+// storeHashesHex returns the store hashes hex encoded for logging.
+func (c CommitInfo) storeHashesHex() map[string]string {
+	res := make(map[string]string, len(c.StoreHashes))
+	for name, hash := range c.StoreHashes {
+		res[name] = hex.EncodeToString(hash)
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAggregateHashVectors(t *testing.T) {
+	sequence := make([]byte, 32)
+	for i := range sequence {
+		sequence[i] = byte(i)
+	}
+	tests := []struct {
+		name        string
+		storeHashes map[string][]byte
+		want        string
+	}{
+		{
+			name:        "no stores",
+			storeHashes: map[string][]byte{},
+			want:        "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			// sha256(03 "acc" 00 04 "bank" 02 01 02)
+			name:        "empty hash",
+			storeHashes: map[string][]byte{"bank": {1, 2}, "acc": {}},
+			want:        "9c806fcfdfa05fdc2144f085b703c24682e7a906ec6103a3c507446cc1b62a23",
+		},
+		{
+			// sha256(c8 01 "sss..." 20 00 01 .. 1f), the name length takes two varint bytes
+			name:        "long store name",
+			storeHashes: map[string][]byte{strings.Repeat("s", 200): sequence},
+			want:        "90d710d4909224e7b820cf0f09d53f012c3576eb155cc0313a37cab1a65bced5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(aggregateHash(tt.storeHashes)); got != tt.want {
+				t.Errorf("aggregateHash() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateHashStoreOrder(t *testing.T) {
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = fmt.Sprintf("store%02d", i)
+	}
+	// maps built in opposite orders
+	forward := map[string][]byte{}
+	for i, name := range names {
+		forward[name] = []byte{byte(i)}
+	}
+	backward := map[string][]byte{}
+	for i := len(names) - 1; i >= 0; i-- {
+		backward[names[i]] = []byte{byte(i)}
+	}
+	want := aggregateHash(forward)
+	for range 10 {
+		if got := aggregateHash(backward); !bytes.Equal(got, want) {
+			t.Fatalf("aggregateHash depends on store order: %X != %X", got, want)
+		}
+	}
+
+	// the hash depends on which store has which hash
+	backward[names[0]], backward[names[1]] = backward[names[1]], backward[names[0]]
+	if bytes.Equal(aggregateHash(backward), want) {
+		t.Error("aggregateHash is unchanged by swapping the hashes of two stores")
+	}
+}
+
+func TestAggregateHashLengthPrefix(t *testing.T) {
+	a := aggregateHash(map[string][]byte{"ab": []byte("c")})
+	b := aggregateHash(map[string][]byte{"a": []byte("bc")})
+	if bytes.Equal(a, b) {
+		t.Error("moving bytes between a store name and its hash doesn't change the aggregate hash")
+	}
+}
@@ -0,0 +1,135 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+)
+
+// CommitStatsParams configure the rolling statistics of commit durations.
+type CommitStatsParams struct {
+	// Window is the number of most recent versions each rolling summary covers.
+	Window int
+	// Interval is the number of versions between rolling summaries. Zero disables them.
+	Interval int
+}
+
+func (p CommitStatsParams) validate() error {
+	if p.Interval < 0 {
+		return fmt.Errorf("commit-stats-interval must not be negative")
+	}
+	if p.Interval > 0 && p.Window <= 0 {
+		return fmt.Errorf("commit-stats-window must be positive")
+	}
+	return nil
+}
+
+type commitSample struct {
+	version  int64
+	duration time.Duration
+}
+
+// commitLatencies tracks the commit durations of a run. Rolling summaries over the most recent versions
+// are exact, while the summary of the whole run is approximated by a latencyHistogram except for its max.
+type commitLatencies struct {
+	params CommitStatsParams
+	// window is a ring buffer of the most recent samples, next is the index of the oldest once it is full
+	window   []commitSample
+	next     int
+	sinceLog int
+	total    latencyHistogram
+	slowest  commitSample
+}
+
+func newCommitLatencies(params CommitStatsParams) *commitLatencies {
+	return &commitLatencies{
+		params: params,
+		window: make([]commitSample, 0, max(params.Window, 0)),
+	}
+}
+
+// record adds the commit duration of version, logging a rolling summary if one is due.
+func (c *commitLatencies) record(logger *slog.Logger, version int64, d time.Duration) {
+	sample := commitSample{version: version, duration: d}
+	c.total.record(d)
+	if d >= c.slowest.duration {
+		c.slowest = sample
+	}
+	if c.params.Interval == 0 {
+		return
+	}
+
+	if len(c.window) < cap(c.window) {
+		c.window = append(c.window, sample)
+	} else {
+		c.window[c.next] = sample
+		c.next = (c.next + 1) % len(c.window)
+	}
+	c.sinceLog++
+	if c.sinceLog >= c.params.Interval {
+		c.logWindow(logger)
+	}
+}
+
+// flush logs a rolling summary if any version was recorded since the last one.
+func (c *commitLatencies) flush(logger *slog.Logger) {
+	if c.sinceLog > 0 {
+		c.logWindow(logger)
+	}
+}
+
+func (c *commitLatencies) logWindow(logger *slog.Logger) {
+	c.sinceLog = 0
+	if len(c.window) == 0 {
+		return
+	}
+	durations := make([]time.Duration, len(c.window))
+	var sum time.Duration
+	slowest := c.window[0]
+	fromVersion := c.window[0].version
+	toVersion := c.window[0].version
+	for i, s := range c.window {
+		durations[i] = s.duration
+		sum += s.duration
+		if s.duration >= slowest.duration {
+			slowest = s
+		}
+		fromVersion = min(fromVersion, s.version)
+		toVersion = max(toVersion, s.version)
+	}
+	slices.Sort(durations)
+	logger.Info("commit latency",
+		"version", toVersion,
+		"from_version", fromVersion,
+		"to_version", toVersion,
+		"count", len(durations),
+		"mean", sum/time.Duration(len(durations)),
+		"p50", nearestRank(durations, 0.5),
+		"p90", nearestRank(durations, 0.9),
+		"p99", nearestRank(durations, 0.99),
+		"max", slowest.duration,
+		"max_version", slowest.version,
+	)
+}
+
+// nearestRank returns the q-th quantile of sorted durations.
+func nearestRank(sorted []time.Duration, q float64) time.Duration {
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(idx, 0)]
+}
+
+// commitLatencySummary is the loggable summary of all commits of a run.
+type commitLatencySummary struct {
+	latencySummary
+	// MaxVersion is the version with the slowest commit.
+	MaxVersion int64 `json:"max_version"`
+}
+
+func (c *commitLatencies) summary() commitLatencySummary {
+	return commitLatencySummary{
+		latencySummary: c.total.summary(true),
+		MaxVersion:     c.slowest.version,
+	}
+}
@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNearestRank(t *testing.T) {
+	ten := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		sorted []time.Duration
+		q      float64
+		want   time.Duration
+	}{
+		{sorted: []time.Duration{7}, q: 0.5, want: 7},
+		{sorted: []time.Duration{7}, q: 0.99, want: 7},
+		{sorted: ten, q: 0, want: 1},
+		{sorted: ten, q: 0.1, want: 1},
+		{sorted: ten, q: 0.11, want: 2},
+		{sorted: ten, q: 0.5, want: 5},
+		{sorted: ten, q: 0.9, want: 9},
+		{sorted: ten, q: 0.99, want: 10},
+		{sorted: ten, q: 1, want: 10},
+		{sorted: []time.Duration{1, 2, 3}, q: 0.5, want: 2},
+	}
+	for _, tt := range tests {
+		if got := nearestRank(tt.sorted, tt.q); got != tt.want {
+			t.Errorf("nearestRank(%v, %g) = %d, want %d", tt.sorted, tt.q, got, tt.want)
+		}
+	}
+}
+
+// commitLatencyLog is a "commit latency" record as logged by the JSON handler, with durations in nanoseconds.
+type commitLatencyLog struct {
+	Msg         string `json:"msg"`
+	FromVersion int64  `json:"from_version"`
+	ToVersion   int64  `json:"to_version"`
+	Count       int    `json:"count"`
+	Mean        int64  `json:"mean"`
+	P50         int64  `json:"p50"`
+	P90         int64  `json:"p90"`
+	P99         int64  `json:"p99"`
+	Max         int64  `json:"max"`
+	MaxVersion  int64  `json:"max_version"`
+}
+
+func TestCommitLatenciesWindow(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	c := newCommitLatencies(CommitStatsParams{Window: 4, Interval: 3})
+	// durations of versions 1 to 8
+	for i, d := range []time.Duration{40, 10, 30, 20, 80, 50, 60, 70} {
+		c.record(logger, int64(i+1), d)
+	}
+	c.flush(logger)
+	// a second flush without new versions must not log again
+	c.flush(logger)
+
+	want := []commitLatencyLog{
+		// the window isn't full yet
+		{FromVersion: 1, ToVersion: 3, Count: 3, Mean: 26, P50: 30, P90: 40, P99: 40, Max: 40, MaxVersion: 1},
+		{FromVersion: 3, ToVersion: 6, Count: 4, Mean: 45, P50: 30, P90: 80, P99: 80, Max: 80, MaxVersion: 5},
+		// flushed with 2 versions since the last summary
+		{FromVersion: 5, ToVersion: 8, Count: 4, Mean: 65, P50: 60, P90: 80, P99: 80, Max: 80, MaxVersion: 5},
+	}
+	var got []commitLatencyLog
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var l commitLatencyLog
+		if err := dec.Decode(&l); err != nil {
+			t.Fatal(err)
+		}
+		if l.Msg != "commit latency" {
+			t.Fatalf("unexpected log message %q", l.Msg)
+		}
+		l.Msg = ""
+		got = append(got, l)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("rolling summaries = %+v, want %+v", got, want)
+	}
+
+	s := c.summary()
+	if s.Count != 8 || s.Max != 80 || s.MaxVersion != 5 || s.Mean != 45 {
+		t.Errorf("summary = %+v, want count 8, max 80 at version 5 and mean 45", s)
+	}
+}
+
+func TestCommitLatenciesDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	c := newCommitLatencies(CommitStatsParams{Window: 100})
+	for i, d := range []time.Duration{10, 30, 30, 20} {
+		c.record(logger, int64(i+1), d)
+	}
+	c.flush(logger)
+	if buf.Len() != 0 {
+		t.Errorf("logged rolling summaries with a zero interval: %s", buf.String())
+	}
+	// ties go to the later version
+	if s := c.summary(); s.Count != 4 || s.MaxVersion != 3 {
+		t.Errorf("summary = %+v, want count 4 and max at version 3", s)
+	}
+}
+
+func TestCommitStatsParamsValidate(t *testing.T) {
+	tests := []struct {
+		params  CommitStatsParams
+		wantErr bool
+	}{
+		{params: CommitStatsParams{}},
+		{params: CommitStatsParams{Window: 100, Interval: 10}},
+		{params: CommitStatsParams{Interval: -1}, wantErr: true},
+		{params: CommitStatsParams{Interval: 10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.params.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.validate() = %v, want error %t", tt.params, err, tt.wantErr)
+		}
+	}
+}
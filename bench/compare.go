@@ -0,0 +1,152 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// summaryMetric is a metric of a RunSummary which can be compared between runs.
+type summaryMetric struct {
+	higherIsBetter bool
+	// value returns false if the metric is not recorded in the summary.
+	value func(s RunSummary) (float64, bool)
+}
+
+func commitMetric(percentile func(p *CommitPercentiles) float64) summaryMetric {
+	return summaryMetric{value: func(s RunSummary) (float64, bool) {
+		if s.CommitLatency == nil {
+			return 0, false
+		}
+		return percentile(s.CommitLatency), true
+	}}
+}
+
+func nonZero(v float64) (float64, bool) {
+	return v, v != 0
+}
+
+var summaryMetrics = map[string]summaryMetric{
+	"ops_per_sec": {higherIsBetter: true, value: func(s RunSummary) (float64, bool) { return nonZero(s.OpsPerSec) }},
+	"elapsed":     {value: func(s RunSummary) (float64, bool) { return nonZero(float64(s.Elapsed)) }},
+	"max_mem":     {value: func(s RunSummary) (float64, bool) { return nonZero(float64(s.MaxMem)) }},
+	"max_rss":     {value: func(s RunSummary) (float64, bool) { return nonZero(float64(s.MaxRSS)) }},
+	"max_disk":    {value: func(s RunSummary) (float64, bool) { return nonZero(float64(s.MaxDisk)) }},
+	"commit_p50":  commitMetric(func(p *CommitPercentiles) float64 { return float64(p.P50) }),
+	"commit_p90":  commitMetric(func(p *CommitPercentiles) float64 { return float64(p.P90) }),
+	"commit_p99":  commitMetric(func(p *CommitPercentiles) float64 { return float64(p.P99) }),
+	"commit_max":  commitMetric(func(p *CommitPercentiles) float64 { return float64(p.Max) }),
+}
+
+// SummaryMetrics returns the names of the metrics which can be passed to CompareRuns.
+// Durations are compared in nanoseconds and sizes in bytes.
+func SummaryMetrics() []string {
+	names := make([]string, 0, len(summaryMetrics))
+	for name := range summaryMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricComparison is the comparison of one metric of a candidate run with the same run of a baseline.
+type MetricComparison struct {
+	Run       string  `json:"run"`
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	// Change is the relative change from the baseline, e.g. -0.1 if the candidate is 10% lower.
+	Change    float64 `json:"change"`
+	Threshold float64 `json:"threshold"`
+	// Skipped is set if the metric is not recorded by either run, in which case it can't regress.
+	Skipped bool `json:"skipped,omitempty"`
+	// Regressed is set if the metric is worse than the baseline by more than the threshold.
+	Regressed bool `json:"regressed"`
+}
+
+// RunComparison is the comparison of a candidate run with the run of the same name in a baseline.
+type RunComparison struct {
+	Run string `json:"run"`
+	// Failure describes why the runs couldn't be compared, e.g. because the candidate run is missing.
+	Failure string             `json:"failure,omitempty"`
+	Metrics []MetricComparison `json:"metrics,omitempty"`
+}
+
+// Regressed returns whether the candidate run is missing, incomplete or regressed in any metric.
+func (c RunComparison) Regressed() bool {
+	if c.Failure != "" {
+		return true
+	}
+	for _, m := range c.Metrics {
+		if m.Regressed {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareRuns compares each baseline run with the candidate run of the same name. thresholds maps the
+// names of the compared metrics to the largest relative change in the worse direction which is not
+// a regression, e.g. 0.05 to allow a 5% drop in ops_per_sec or a 5% increase of max_rss. A change of exactly
+// the threshold is not a regression. The thresholds are global, i.e. they apply to all runs alike, so they must
+// allow for the noise of the noisiest run; compare noisy runs separately to give them larger thresholds.
+// Candidate runs without a baseline are ignored.
+func CompareRuns(baseline, candidate []RunSummary, thresholds map[string]float64) ([]RunComparison, error) {
+	metricNames := make([]string, 0, len(thresholds))
+	for name, threshold := range thresholds {
+		if _, ok := summaryMetrics[name]; !ok {
+			return nil, fmt.Errorf("unknown metric %q, expected one of %s", name, strings.Join(SummaryMetrics(), ", "))
+		}
+		if threshold < 0 {
+			return nil, fmt.Errorf("threshold of %s must not be negative", name)
+		}
+		metricNames = append(metricNames, name)
+	}
+	sort.Strings(metricNames)
+
+	candidates := make(map[string]RunSummary, len(candidate))
+	for _, s := range candidate {
+		candidates[s.Name] = s
+	}
+
+	res := make([]RunComparison, 0, len(baseline))
+	for _, base := range baseline {
+		comparison := RunComparison{Run: base.Name}
+		cand, ok := candidates[base.Name]
+		switch {
+		case !ok:
+			comparison.Failure = "missing from candidate"
+		case base.Complete && !cand.Complete:
+			comparison.Failure = "candidate run is incomplete"
+		}
+		if comparison.Failure != "" {
+			res = append(res, comparison)
+			continue
+		}
+		for _, name := range metricNames {
+			comparison.Metrics = append(comparison.Metrics, compareMetric(base, cand, name, thresholds[name]))
+		}
+		res = append(res, comparison)
+	}
+	return res, nil
+}
+
+func compareMetric(base, cand RunSummary, name string, threshold float64) MetricComparison {
+	metric := summaryMetrics[name]
+	res := MetricComparison{Run: base.Name, Metric: name, Threshold: threshold}
+	baseValue, baseOk := metric.value(base)
+	candValue, candOk := metric.value(cand)
+	res.Baseline = baseValue
+	res.Candidate = candValue
+	if !baseOk || !candOk {
+		res.Skipped = true
+		return res
+	}
+	res.Change = (candValue - baseValue) / baseValue
+	if metric.higherIsBetter {
+		res.Regressed = res.Change < -threshold
+	} else {
+		res.Regressed = res.Change > threshold
+	}
+	return res
+}
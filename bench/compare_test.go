@@ -0,0 +1,187 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareMetric(t *testing.T) {
+	base := RunSummary{
+		Name:      "run",
+		Complete:  true,
+		OpsPerSec: 1000,
+		MaxRSS:    100,
+		CommitLatency: &CommitPercentiles{
+			P99: 200 * time.Millisecond,
+		},
+	}
+	tests := []struct {
+		name          string
+		metric        string
+		threshold     float64
+		candidate     func(s *RunSummary)
+		wantChange    float64
+		wantSkipped   bool
+		wantRegressed bool
+	}{
+		{
+			name:       "unchanged",
+			metric:     "ops_per_sec",
+			threshold:  0,
+			candidate:  func(s *RunSummary) {},
+			wantChange: 0,
+		},
+		{
+			name:       "drop of exactly the threshold",
+			metric:     "ops_per_sec",
+			threshold:  0.05,
+			candidate:  func(s *RunSummary) { s.OpsPerSec = 950 },
+			wantChange: -0.05,
+		},
+		{
+			name:          "drop beyond the threshold",
+			metric:        "ops_per_sec",
+			threshold:     0.05,
+			candidate:     func(s *RunSummary) { s.OpsPerSec = 949 },
+			wantChange:    -0.051,
+			wantRegressed: true,
+		},
+		{
+			name:       "improvement beyond the threshold",
+			metric:     "ops_per_sec",
+			threshold:  0.05,
+			candidate:  func(s *RunSummary) { s.OpsPerSec = 2000 },
+			wantChange: 1,
+		},
+		{
+			name:          "any drop with a zero threshold",
+			metric:        "ops_per_sec",
+			threshold:     0,
+			candidate:     func(s *RunSummary) { s.OpsPerSec = 999 },
+			wantChange:    -0.001,
+			wantRegressed: true,
+		},
+		{
+			name:       "increase of exactly the threshold",
+			metric:     "max_rss",
+			threshold:  0.25,
+			candidate:  func(s *RunSummary) { s.MaxRSS = 125 },
+			wantChange: 0.25,
+		},
+		{
+			name:          "increase beyond the threshold",
+			metric:        "max_rss",
+			threshold:     0.25,
+			candidate:     func(s *RunSummary) { s.MaxRSS = 126 },
+			wantChange:    0.26,
+			wantRegressed: true,
+		},
+		{
+			name:       "decrease beyond the threshold",
+			metric:     "max_rss",
+			threshold:  0.25,
+			candidate:  func(s *RunSummary) { s.MaxRSS = 50 },
+			wantChange: -0.5,
+		},
+		{
+			name:          "commit latency increase",
+			metric:        "commit_p99",
+			threshold:     0.5,
+			candidate:     func(s *RunSummary) { s.CommitLatency = &CommitPercentiles{P99: 400 * time.Millisecond} },
+			wantChange:    1,
+			wantRegressed: true,
+		},
+		{
+			name:        "not recorded by the candidate",
+			metric:      "commit_p99",
+			threshold:   0,
+			candidate:   func(s *RunSummary) { s.CommitLatency = nil },
+			wantSkipped: true,
+		},
+		{
+			name:        "zero is not recorded",
+			metric:      "max_rss",
+			threshold:   0,
+			candidate:   func(s *RunSummary) { s.MaxRSS = 0 },
+			wantSkipped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cand := base
+			tt.candidate(&cand)
+			res, err := CompareRuns([]RunSummary{base}, []RunSummary{cand}, map[string]float64{tt.metric: tt.threshold})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res) != 1 || len(res[0].Metrics) != 1 {
+				t.Fatalf("CompareRuns() = %+v, want one comparison of one metric", res)
+			}
+			m := res[0].Metrics[0]
+			if m.Metric != tt.metric || m.Threshold != tt.threshold {
+				t.Errorf("compared %s with threshold %g, want %s with %g", m.Metric, m.Threshold, tt.metric, tt.threshold)
+			}
+			if !tt.wantSkipped && !approxEqual(m.Change, tt.wantChange) {
+				t.Errorf("change = %g, want %g", m.Change, tt.wantChange)
+			}
+			if m.Skipped != tt.wantSkipped || m.Regressed != tt.wantRegressed {
+				t.Errorf("skipped, regressed = %t, %t, want %t, %t", m.Skipped, m.Regressed, tt.wantSkipped, tt.wantRegressed)
+			}
+			if res[0].Regressed() != tt.wantRegressed {
+				t.Errorf("run regressed = %t, want %t", res[0].Regressed(), tt.wantRegressed)
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
+
+func TestCompareRuns(t *testing.T) {
+	complete := func(name string, opsPerSec float64) RunSummary {
+		return RunSummary{Name: name, Complete: true, OpsPerSec: opsPerSec}
+	}
+	incomplete := RunSummary{Name: "b", OpsPerSec: 100}
+	baseline := []RunSummary{complete("a", 100), complete("b", 100), complete("c", 100), {Name: "d", OpsPerSec: 100}}
+	candidate := []RunSummary{complete("a", 80), incomplete, complete("new", 1), {Name: "d", OpsPerSec: 100}}
+	thresholds := map[string]float64{"ops_per_sec": 0.1, "max_mem": 0.1}
+
+	got, err := CompareRuns(baseline, candidate, thresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RunComparison{
+		{Run: "a", Metrics: []MetricComparison{
+			{Run: "a", Metric: "max_mem", Threshold: 0.1, Skipped: true},
+			{Run: "a", Metric: "ops_per_sec", Baseline: 100, Candidate: 80, Change: -0.2, Threshold: 0.1, Regressed: true},
+		}},
+		{Run: "b", Failure: "candidate run is incomplete"},
+		{Run: "c", Failure: "missing from candidate"},
+		// an incomplete baseline is compared with whatever the candidate did
+		{Run: "d", Metrics: []MetricComparison{
+			{Run: "d", Metric: "max_mem", Threshold: 0.1, Skipped: true},
+			{Run: "d", Metric: "ops_per_sec", Baseline: 100, Candidate: 100, Threshold: 0.1},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareRuns() = %+v, want %+v", got, want)
+	}
+	for i, wantRegressed := range []bool{true, true, true, false} {
+		if got[i].Regressed() != wantRegressed {
+			t.Errorf("run %s regressed = %t, want %t", got[i].Run, got[i].Regressed(), wantRegressed)
+		}
+	}
+}
+
+func TestCompareRunsInvalidThresholds(t *testing.T) {
+	for _, thresholds := range []map[string]float64{
+		{"ops_per_sec": -0.1},
+		{"latency": 0.1},
+	} {
+		if _, err := CompareRuns(nil, nil, thresholds); err == nil {
+			t.Errorf("CompareRuns with thresholds %v succeeded, want an error", thresholds)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Points in a run at which the crash command kills the runner process.
+const (
+	// KillApply kills the runner while it applies the changes of a version.
+	KillApply = "apply"
+	// KillCommit kills the runner while it commits a version.
+	KillCommit = "commit"
+	// KillSnapshot kills the runner while it rewrites a snapshot in the background, only supported by memiavl.
+	KillSnapshot = "snapshot"
+	// KillAny chooses between KillApply and KillCommit for every crash.
+	KillAny = "any"
+)
+
+// log messages of the runner and trees which mark the kill points
+const (
+	msgApplying         = "applying changeset"
+	msgCommitting       = "applied all changes, commiting"
+	msgCommitted        = "committed version"
+	msgSnapshotStarted  = "start rewriting snapshot"
+	msgSnapshotFinished = "finished rewriting snapshot"
+)
+
+type crashParams struct {
+	Crashes   int
+	KillPoint string
+	// ReferenceHashes is a hash log written by the verify command which recovered versions are checked against.
+	ReferenceHashes string
+}
+
+func (p crashParams) validate() error {
+	switch p.KillPoint {
+	case KillApply, KillCommit, KillSnapshot, KillAny:
+	default:
+		return fmt.Errorf("unknown kill point %q, expected one of %s, %s, %s or %s",
+			p.KillPoint, KillApply, KillCommit, KillSnapshot, KillAny)
+	}
+	if p.Crashes <= 0 {
+		return fmt.Errorf("crashes must be positive")
+	}
+	if p.ReferenceHashes == "" {
+		return fmt.Errorf("reference-hashes is required")
+	}
+	return nil
+}
+
+func newCrashCommand(treeType string, cfg RunConfig) *cobra.Command {
+	var flags runFlags
+	var params crashParams
+	cmd := &cobra.Command{
+		Use:   "crash",
+		Short: "Repeatedly kills a runner process applying changesets and checks that the db recovers at a consistent version.",
+		Long: `Repeatedly kills a runner process applying changesets and checks that the db recovers at a consistent version.
+
+The runner is started as a child process with the bench command and killed with SIGKILL at a random point
+of a random version. The db is then reopened with the same tree loader and must load at a version no older
+than the last version the runner reported as committed. The recovered state is checked by applying the next
+version and comparing its hashes with the reference hash log, and the next child continues from there.`,
+	}
+	flags.register(cmd)
+	cmd.Flags().IntVar(&params.Crashes, "crashes", 10, "Number of times the runner is killed.")
+	cmd.Flags().StringVar(&params.KillPoint, "kill-point", KillAny, "Where the runner is killed. One of 'apply', 'commit', 'snapshot' (memiavl only) or 'any' for either apply or commit.")
+	cmd.Flags().StringVar(&params.ReferenceHashes, "reference-hashes", "", "Hash log produced by the verify command which recovered versions are checked against.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		err := params.validate()
+		if err != nil {
+			return err
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("error locating runner executable: %w", err)
+		}
+		verifier, err := newHashVerifier(io.Discard, params.ReferenceHashes)
+		if err != nil {
+			return fmt.Errorf("error loading reference hashes: %w", err)
+		}
+
+		loaded, err := flags.setup(treeType, cfg)
+		if err != nil {
+			return err
+		}
+		defer loaded.closeLog()
+
+		c := &crashTest{
+			executable:    executable,
+			flags:         flags,
+			cfg:           cfg,
+			params:        params,
+			changesetDir:  flags.changesetDir,
+			loaderParams:  loaded.params.LoaderParams,
+			targetVersion: loaded.params.TargetVersion,
+			verifier:      verifier,
+			logger:        loaded.params.Logger,
+			rng:           rand.New(rand.NewPCG(5, 0)),
+		}
+		return c.run()
+	}
+	return cmd
+}
+
+// crashTest kills runner processes and checks the recovery of the db they write to.
+type crashTest struct {
+	executable    string
+	flags         runFlags
+	cfg           RunConfig
+	params        crashParams
+	changesetDir  string
+	loaderParams  LoaderParams
+	targetVersion int64
+	verifier      *hashVerifier
+	logger        *slog.Logger
+	rng           *rand.Rand
+
+	// durations observed in the runner logs, used to pick a kill time within the next occurrence
+	applyDuration    time.Duration
+	commitDuration   time.Duration
+	snapshotDuration time.Duration
+}
+
+// childResult describes how a runner process ended.
+type childResult struct {
+	killed bool
+	// killVersion is the version which was being applied or committed when the kill was scheduled
+	killVersion int64
+	killDelay   time.Duration
+	// lastCommitted is the last version the runner reported as committed, i.e. for which Commit returned
+	lastCommitted int64
+}
+
+// recovery describes the state of the db after a crash.
+type recovery struct {
+	// loadedVersion is the version the db loaded at
+	loadedVersion int64
+	// version is the version of the db after recovery, the version after loadedVersion if it was verified
+	version      int64
+	loadDuration time.Duration
+	verified     bool
+}
+
+func (c *crashTest) run() error {
+	rec, err := c.recover()
+	if err != nil {
+		return err
+	}
+
+	var lostVersions, unverified, missed int
+	crashes := 0
+	for crashes < c.params.Crashes {
+		if rec.version >= c.targetVersion {
+			c.logger.Warn("reached target version before all crashes", "crashes", crashes, "target_version", c.targetVersion)
+			break
+		}
+
+		point := c.params.KillPoint
+		if point == KillAny {
+			point = []string{KillApply, KillCommit}[c.rng.IntN(2)]
+		}
+		// spread the remaining crashes evenly over the remaining versions
+		span := (c.targetVersion - rec.version) / int64(c.params.Crashes-crashes)
+		crashVersion := rec.version + 1 + c.rng.Int64N(max(span, 1))
+
+		res, err := c.runChild(point, crashVersion)
+		if err != nil {
+			return err
+		}
+		if !res.killed {
+			missed++
+			c.logger.Warn("runner reached target version before being killed", "kill_point", point, "crash_version", crashVersion)
+			rec, err = c.recover()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		crashes++
+		lastCommitted := max(res.lastCommitted, rec.version)
+
+		rec, err = c.recover()
+		attrs := []any{
+			"crash", crashes,
+			"kill_point", point,
+			"kill_version", res.killVersion,
+			"kill_delay", res.killDelay,
+			"last_committed", lastCommitted,
+			"recovered_version", rec.loadedVersion,
+			"load_duration", rec.loadDuration,
+			"verified", rec.verified,
+		}
+		switch {
+		case err != nil:
+			c.logger.Error("crash recovery failed", append(attrs, "error", err)...)
+			return fmt.Errorf("crash %d: %w", crashes, err)
+		case rec.loadedVersion > lastCommitted+1:
+			// only the version being committed when the runner was killed may have been committed without being reported
+			err = fmt.Errorf("recovered version %d is ahead of the last committed version %d", rec.loadedVersion, lastCommitted)
+			c.logger.Error("crash recovery failed", append(attrs, "error", err)...)
+			return fmt.Errorf("crash %d: %w", crashes, err)
+		case rec.loadedVersion < lastCommitted:
+			lostVersions += int(lastCommitted - rec.loadedVersion)
+			c.logger.Error("crash recovery lost committed versions", append(attrs, "lost_versions", lastCommitted-rec.loadedVersion)...)
+		case !rec.verified:
+			unverified++
+			c.logger.Warn("crash recovery not verified", attrs...)
+		default:
+			c.logger.Info("crash recovery", attrs...)
+		}
+	}
+
+	c.logger.Info("crash test complete",
+		"crashes", crashes,
+		"lost_versions", lostVersions,
+		"unverified", unverified,
+		"missed", missed,
+	)
+	if lostVersions > 0 {
+		return fmt.Errorf("%d committed versions were lost over %d crashes", lostVersions, crashes)
+	}
+	return nil
+}
+
+// recover loads the db and checks it against the reference by applying the next version,
+// which must produce the reference hashes if the loaded state is consistent.
+// A mismatch is returned as a *Divergence error.
+func (c *crashTest) recover() (rec recovery, err error) {
+	start := time.Now()
+	tree, err := c.cfg.TreeLoader(c.loaderParams)
+	if err != nil {
+		return rec, fmt.Errorf("error loading tree: %w", err)
+	}
+	rec.loadDuration = time.Since(start)
+	defer func() {
+		closeErr := tree.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("error closing tree: %w", closeErr)
+		}
+		// the runner started next needs the memory held by the tree
+		debug.FreeOSMemory()
+	}()
+
+	rec.loadedVersion = tree.Version()
+	rec.version = rec.loadedVersion
+	if rec.version >= c.targetVersion {
+		return rec, nil
+	}
+
+	next := rec.version + 1
+	commitInfo, _, err := applyVersion(c.logger, tree, c.changesetDir, next, &workloads{}, nil)
+	if err != nil {
+		return rec, fmt.Errorf("error applying version %d after recovery: %w", next, err)
+	}
+	err = c.verifier.check(next, commitInfo)
+	if err != nil {
+		return rec, err
+	}
+	rec.verified = true
+	rec.version = next
+	return rec, nil
+}
+
+// runChild runs the bench command in a child process from the current version of the db to the target version
+// and kills it at point once crashVersion is reached.
+func (c *crashTest) runChild(point string, crashVersion int64) (childResult, error) {
+	args := []string{
+		"bench",
+		"--db-dir", c.flags.treeDir,
+		"--changeset-dir", c.flags.changesetDir,
+		"--target-version", strconv.FormatInt(c.targetVersion, 10),
+		"--log-type", "json",
+	}
+	if c.flags.treeOptions != "" {
+		args = append(args, "--db-options", c.flags.treeOptions)
+	}
+	cmd := exec.Command(c.executable, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return childResult{}, err
+	}
+	c.logger.Info("starting runner", "cmd", cmd.String(), "kill_point", point, "crash_version", crashVersion)
+	err = cmd.Start()
+	if err != nil {
+		return childResult{}, fmt.Errorf("error starting runner: %w", err)
+	}
+
+	var res childResult
+	var killed atomic.Bool
+	var killTimer *time.Timer
+	arm := func(version int64, expected time.Duration) {
+		if killTimer != nil {
+			return
+		}
+		res.killVersion = version
+		// kill at a random time within the expected duration of the phase
+		if expected > 0 {
+			res.killDelay = time.Duration(c.rng.Int64N(int64(expected)))
+		}
+		killTimer = time.AfterFunc(res.killDelay, func() {
+			killed.Store(true)
+			_ = cmd.Process.Kill()
+		})
+	}
+
+	var current int64
+	var applyStart, commitStart, snapshotStart time.Time
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Msg     string `json:"msg"`
+			Version int64  `json:"version"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		now := time.Now()
+		switch entry.Msg {
+		case msgApplying:
+			current = entry.Version
+			applyStart = now
+			if point == KillApply && current >= crashVersion {
+				arm(current, c.applyDuration)
+			}
+		case msgCommitting:
+			c.applyDuration = now.Sub(applyStart)
+			commitStart = now
+			if point == KillCommit && current >= crashVersion {
+				arm(current, c.commitDuration)
+			}
+		case msgCommitted:
+			c.commitDuration = now.Sub(commitStart)
+			res.lastCommitted = entry.Version
+		case msgSnapshotStarted:
+			snapshotStart = now
+			if point == KillSnapshot && current >= crashVersion {
+				arm(current, c.snapshotDuration)
+			}
+		case msgSnapshotFinished:
+			c.snapshotDuration = now.Sub(snapshotStart)
+		}
+	}
+	// drain the rest of the output if it could not be scanned so the runner does not block
+	_, _ = io.Copy(io.Discard, stdout)
+
+	err = cmd.Wait()
+	if killTimer != nil {
+		killTimer.Stop()
+	}
+	// the kill may race with the runner exiting by itself
+	res.killed = killed.Load() && err != nil
+	if err != nil && !res.killed {
+		return res, fmt.Errorf("runner failed: %w: %s", err, stderr.String())
+	}
+	return res, nil
+}
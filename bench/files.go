@@ -5,8 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protodelim"
 )
 
+// changesetUnmarshalOptions don't limit the size of a StoreKVPair, real chain state can hold values
+// larger than the protodelim default of 4MiB, e.g. contract code.
+var changesetUnmarshalOptions = protodelim.UnmarshalOptions{MaxSize: -1}
+
 func changesetDataFilename(dataDir string, version int64) string {
 	return filepath.Join(dataDir, fmt.Sprintf("%09d.delimpb", version))
 }
@@ -19,6 +25,10 @@ type changesetInfo struct {
 	Versions    int64         `json:"versions"`
 	StoreNames  []string      `json:"store_names"`
 	StoreParams []StoreParams `json:"store_params"`
+	// Source describes where changesets which weren't generated came from, e.g. the dir they were imported from.
+	Source string `json:"source,omitempty"`
+	// StartHeight is the block height of version 1 of changesets which were taken from a chain.
+	StartHeight int64 `json:"start_height,omitempty"`
 }
 
 func writeChangesetInfo(dataDir string, info changesetInfo) error {
@@ -50,6 +50,34 @@ type StoreParams struct {
 	ChangePerVersion int `json:"change_per_version"`
 	// DeleteFraction is the fraction of ChangePerVersion that are deletes.
 	DeleteFraction float64 `json:"delete_fraction"`
+	// AccessDistribution is the distribution of the existing keys targeted by updates and deletes,
+	// one of AccessUniform, AccessZipf, AccessHotSet or AccessRecent. Empty is AccessUniform.
+	// The other distributions rank keys by age, so that which keys are hot doesn't change as keys are created.
+	AccessDistribution string `json:"access_distribution,omitempty"`
+	// ZipfSkew is the skew of AccessZipf and must be > 1, the higher the more accesses go to the oldest keys.
+	ZipfSkew float64 `json:"zipf_skew,omitempty"`
+	// HotSetFraction is the fraction of the existing keys in the hot set of AccessHotSet, the oldest ones.
+	HotSetFraction float64 `json:"hot_set_fraction,omitempty"`
+	// HotSetProbability is the probability that AccessHotSet targets a key of the hot set.
+	HotSetProbability float64 `json:"hot_set_probability,omitempty"`
+	// RecencyMean is the mean number of keys created after the keys targeted by AccessRecent.
+	RecencyMean float64 `json:"recency_mean,omitempty"`
+	// KeySchema optionally generates structured keys from its segments in order instead of random bytes,
+	// e.g. a fixed prefix, a length-prefixed address and a denom. KeyMean and KeyStdDev are ignored if set.
+	KeySchema []KeySegment `json:"key_schema,omitempty"`
+	// ValueMode is how values are generated, one of ValueRandom, ValueCompressible, ValueProtobuf or ValueCounter.
+	// Empty is ValueRandom, whose values don't compress at all unlike the values of a real chain.
+	ValueMode string `json:"value_mode,omitempty"`
+	// ValueCompressibility is the fraction of the bytes of ValueCompressible values which repeat earlier bytes
+	// of the value, in [0, 1). Measured with snappy on 4 KiB blocks, values of 64 bytes and more compress to
+	// 0.87, 0.57, 0.32 and 0.17 of their size for 0.25, 0.5, 0.75 and 0.9, shorter values compress less.
+	ValueCompressibility float64 `json:"value_compressibility,omitempty"`
+	// ValueReuse is the probability that an update writes the last value of the key changed in ValueMutations
+	// bytes instead of a new value, like a balance or a counter field changing in a record. The changes accumulate
+	// over the updates of a key. The last value of every key is kept in memory if set.
+	ValueReuse float64 `json:"value_reuse,omitempty"`
+	// ValueMutations is the number of bytes changed in reused values.
+	ValueMutations int `json:"value_mutations,omitempty"`
 }
 
 func GenerateChangesets(g TreeParams, outDir string) error {
@@ -67,7 +95,11 @@ func GenerateChangesets(g TreeParams, outDir string) error {
 	multiStoreState := map[string]*storeState{}
 	storeNames := make([]string, 0, len(g.StoreParams))
 	for _, gen := range g.StoreParams {
-		multiStoreState[gen.StoreKey] = newStoreState(gen)
+		st, err := newStoreState(gen)
+		if err != nil {
+			return fmt.Errorf("invalid params of store %s: %w", gen.StoreKey, err)
+		}
+		multiStoreState[gen.StoreKey] = st
 		fmt.Printf("Store %s params: %+v\n", gen.StoreKey, gen)
 		storeNames = append(storeNames, gen.StoreKey)
 	}
@@ -122,16 +154,41 @@ type storeState struct {
 	existingKeys      *btree.BTreeG[[]byte]
 	createsPerVersion float64
 	createAccumulator float64
+	// ages is nil for uniform access
+	ages *keyAges
+	// schema is nil for random keys
+	schema *keySchema
+	values *valueGen
+	// version is the version being generated
+	version int64
 }
 
-func newStoreState(c StoreParams) *storeState {
-	return &storeState{
+func newStoreState(c StoreParams) (*storeState, error) {
+	st := &storeState{
 		gen: c,
 		existingKeys: btree.NewBTreeG(func(a, b []byte) bool {
 			return bytes.Compare(a, b) < 0
 		}),
 		createsPerVersion: float64(c.FinalSize-c.InitialSize) / float64(c.Versions-1),
 	}
+	pick, err := c.accessPicker()
+	if err != nil {
+		return nil, err
+	}
+	st.values, err = newValueGen(c)
+	if err != nil {
+		return nil, err
+	}
+	if pick != nil {
+		st.ages = newKeyAges(pick)
+	}
+	if len(c.KeySchema) != 0 {
+		st.schema, err = newKeySchema(c.StoreKey, c.KeySchema)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
 }
 
 type opType int
@@ -250,6 +307,7 @@ type changesetPlan struct {
 }
 
 func (c *storeState) genChangesetPlan(version int64) changesetPlan {
+	c.version = version
 	if version == 1 {
 		return changesetPlan{
 			deletes: 0,
@@ -277,11 +335,24 @@ var NoKeys = errors.New("no keys")
 
 func (c *storeState) genCreate(w io.Writer, rng *rand.Rand) error {
 	key := c.genKey(rng)
-	for c.has(key) {
+	for attempts := 1; c.has(key); attempts++ {
+		if attempts == maxKeyAttempts {
+			return fmt.Errorf("no new key after %d attempts, the key schema can't generate more distinct keys", attempts)
+		}
 		key = c.genKey(rng)
 	}
 	c.existingKeys.Set(key)
-	return c.writeKVStorePair(w, key, c.genValue(rng), false)
+	c.ages.add(key)
+	return c.writeKVStorePair(w, key, c.values.create(rng, key), false)
+}
+
+// pickKey returns an existing key according to the access distribution of the store.
+func (c *storeState) pickKey(rng *rand.Rand) ([]byte, bool) {
+	if c.ages != nil {
+		return c.ages.pick(rng)
+	}
+	idx := rng.IntN(c.existingKeys.Len())
+	return c.existingKeys.GetAt(idx)
 }
 
 func (c *storeState) genUpdate(w io.Writer, rng *rand.Rand) error {
@@ -289,12 +360,11 @@ func (c *storeState) genUpdate(w io.Writer, rng *rand.Rand) error {
 	if n == 0 {
 		return NoKeys
 	}
-	idx := rng.IntN(n)
-	key, ok := c.existingKeys.GetAt(idx)
+	key, ok := c.pickKey(rng)
 	if !ok {
 		return fmt.Errorf("logic error: no key to update")
 	}
-	return c.writeKVStorePair(w, key, c.genValue(rng), false)
+	return c.writeKVStorePair(w, key, c.values.update(rng, key), false)
 }
 
 func (c *storeState) genDelete(w io.Writer, rng *rand.Rand) error {
@@ -302,12 +372,13 @@ func (c *storeState) genDelete(w io.Writer, rng *rand.Rand) error {
 	if n == 0 {
 		return NoKeys
 	}
-	idx := rng.IntN(n)
-	key, ok := c.existingKeys.GetAt(idx)
+	key, ok := c.pickKey(rng)
 	if !ok {
 		return fmt.Errorf("logic error: no key to delete")
 	}
 	c.existingKeys.Delete(key)
+	c.ages.delete(key)
+	c.values.delete(key)
 	return c.writeKVStorePair(w, key, nil, true)
 }
 
@@ -322,19 +393,27 @@ func (c *storeState) writeKVStorePair(w io.Writer, key, value []byte, delete boo
 }
 
 func (c *storeState) genKey(rng *rand.Rand) []byte {
+	if c.schema != nil {
+		return c.schema.gen(rng, c.version)
+	}
 	return genBytes(rng, c.gen.KeyMean, c.gen.KeyStdDev)
 }
 
-func (c *storeState) genValue(rng *rand.Rand) []byte {
-	return genBytes(rng, c.gen.ValueMean, c.gen.ValueStdDev)
-}
-
 func (c *storeState) has(key []byte) bool {
 	_, ok := c.existingKeys.Get(key)
 	return ok
 }
 
 func genBytes(rng *rand.Rand, mean, stdDev int) []byte {
+	length := genLength(rng, mean, stdDev)
+	b := make([]byte, length)
+	for i := 0; i < length; i++ {
+		b[i] = byte(rng.IntN(256))
+	}
+	return b
+}
+
+func genLength(rng *rand.Rand, mean, stdDev int) int {
 	length := int(rng.NormFloat64()*float64(stdDev) + float64(mean))
 	// length must be at least 1
 	// explanation: normal distribution is a poor approximation of certain data sets where std dev is skewed
@@ -349,9 +428,5 @@ func genBytes(rng *rand.Rand, mean, stdDev int) []byte {
 			length = 1
 		}
 	}
-	b := make([]byte, length)
-	for i := 0; i < length; i++ {
-		b[i] = byte(rng.IntN(256))
-	}
-	return b
+	return length
 }
@@ -0,0 +1,53 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// generateHash generates versions changesets from a fixed seed and returns the hash of their data files.
+func generateHash(t *testing.T, versions int64, params ...StoreParams) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "changesets")
+	err := GenerateChangesets(TreeParams{
+		RandSource:  rand.NewPCG(0, 0),
+		Versions:    versions,
+		StoreParams: params,
+	}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	for version := int64(1); version <= versions; version++ {
+		bz, err := os.ReadFile(changesetDataFilename(out, version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write(bz)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TestGenerateChangesetsDefaultProfile checks that stores which don't set the access distribution, key schema
+// and value mode are generated exactly as before these options were added, so that existing changesets can be
+// regenerated.
+func TestGenerateChangesetsDefaultProfile(t *testing.T) {
+	got := generateHash(t, 5,
+		StoreParams{
+			StoreKey: "bank", KeyMean: 20, KeyStdDev: 3, ValueMean: 40, ValueStdDev: 60,
+			InitialSize: 50, FinalSize: 80, Versions: 5, ChangePerVersion: 30, DeleteFraction: 0.25,
+		},
+		StoreParams{
+			StoreKey: "staking", KeyMean: 12, KeyStdDev: 1, ValueMean: 100, ValueStdDev: 10,
+			InitialSize: 20, FinalSize: 20, Versions: 5, ChangePerVersion: 10, DeleteFraction: 0.1,
+		},
+	)
+	const want = "393c6bfb043a68f514c314573dd4b72c9cd7a3cd6e2f77913e97f3599314f651"
+	if got != want {
+		t.Errorf("changesets of the default profile hash to %s, want %s", got, want)
+	}
+}
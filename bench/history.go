@@ -0,0 +1,194 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Version distributions for historical queries.
+const (
+	HistoryUniform = "uniform"
+	// HistoryRecent favors versions close to the latest one.
+	HistoryRecent = "recent"
+	// HistoryOld favors versions close to the first one.
+	HistoryOld = "old"
+)
+
+// historyAgeBands is the number of equally sized version age ranges historical query stats are reported for.
+const historyAgeBands = 10
+
+// HistoryParams configure the historical queries performed after all changesets have been applied.
+type HistoryParams struct {
+	// Queries is the number of historical queries. Zero disables them.
+	Queries int
+	// Distribution selects how queried versions are chosen, one of HistoryUniform, HistoryRecent or HistoryOld.
+	Distribution string
+}
+
+func (p HistoryParams) validate() error {
+	switch p.Distribution {
+	case HistoryUniform, HistoryRecent, HistoryOld:
+		return nil
+	default:
+		return fmt.Errorf("unknown history distribution %q, expected one of %s, %s or %s",
+			p.Distribution, HistoryUniform, HistoryRecent, HistoryOld)
+	}
+}
+
+// historyStats are the stats of the historical queries of a range of version ages.
+type historyStats struct {
+	latency     latencyHistogram
+	queries     int
+	unavailable int
+	found       int
+	lastErr     error
+}
+
+func (s *historyStats) merge(other *historyStats) {
+	s.latency.merge(&other.latency)
+	s.queries += other.queries
+	s.unavailable += other.unavailable
+	s.found += other.found
+	if other.lastErr != nil {
+		s.lastErr = other.lastErr
+	}
+}
+
+func (s *historyStats) successRate() float64 {
+	if s.queries == 0 {
+		return 0
+	}
+	return float64(s.queries-s.unavailable) / float64(s.queries)
+}
+
+type historyBandSummary struct {
+	MinAge      int64          `json:"min_age"`
+	MaxAge      int64          `json:"max_age"`
+	Queries     int            `json:"queries"`
+	Unavailable int            `json:"unavailable"`
+	SuccessRate float64        `json:"success_rate"`
+	Latency     latencySummary `json:"latency"`
+}
+
+// historyWorkload queries keys which have been written during the run at randomly chosen past versions,
+// like an archive node serving historical queries.
+type historyWorkload struct {
+	reader Reader
+	params HistoryParams
+	rng    *rand.Rand
+	keys   *keySamples
+}
+
+func newHistoryWorkload(tree Tree, params HistoryParams) (*historyWorkload, error) {
+	reader, ok := tree.(Reader)
+	if !ok {
+		return nil, fmt.Errorf("tree of type %T does not implement Reader", tree)
+	}
+	err := params.validate()
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(3, 0))
+	return &historyWorkload{
+		reader: reader,
+		params: params,
+		rng:    rng,
+		keys:   newKeySamples(rng),
+	}, nil
+}
+
+func (w *historyWorkload) afterUpdate(storeKey string, key, value []byte, delete bool) {
+	w.keys.update(storeKey, key, value, delete)
+}
+
+// randomAge returns the age of the version to query, 0 being the latest version, according to the distribution.
+func (w *historyWorkload) randomAge(versions int64) int64 {
+	u := w.rng.Float64()
+	switch w.params.Distribution {
+	case HistoryRecent:
+		u = u * u * u
+	case HistoryOld:
+		u = 1 - u*u*u
+	}
+	return min(int64(u*float64(versions)), versions-1)
+}
+
+// run performs the historical queries against versions [firstVersion, latestVersion] and logs their stats.
+// Queries of versions which are not available, e.g. because they were pruned, are counted as unavailable
+// rather than failing the run.
+func (w *historyWorkload) run(logger *slog.Logger, firstVersion, latestVersion int64) {
+	if w.keys.empty() || latestVersion < firstVersion {
+		logger.Warn("skipping historical queries, no versions were applied")
+		return
+	}
+	versions := latestVersion - firstVersion + 1
+	bandWidth := int64(math.Ceil(float64(versions) / historyAgeBands))
+	var bands [historyAgeBands]historyStats
+
+	logger.Info("starting historical queries", "queries", w.params.Queries, "distribution", w.params.Distribution,
+		"first_version", firstVersion, "latest_version", latestVersion)
+	progressInterval := max(w.params.Queries/10, 1)
+	for i := 0; i < w.params.Queries; i++ {
+		if i > 0 && i%progressInterval == 0 {
+			logger.Debug("performed historical queries", "count", i)
+		}
+		age := w.randomAge(versions)
+		version := latestVersion - age
+		storeKey, key := w.keys.random(false)
+
+		start := time.Now()
+		value, err := w.reader.GetAt(storeKey, version, key)
+		duration := time.Since(start)
+
+		band := &bands[age/bandWidth]
+		band.queries++
+		if err != nil {
+			band.unavailable++
+			band.lastErr = fmt.Errorf("get of key %X in store %s at version %d: %w", key, storeKey, version, err)
+			continue
+		}
+		// only answered queries are included in latency stats
+		band.latency.record(duration)
+		if value != nil {
+			band.found++
+		}
+	}
+
+	var total historyStats
+	var bandSummaries []historyBandSummary
+	for i := range bands {
+		band := &bands[i]
+		total.merge(band)
+		if band.queries == 0 {
+			continue
+		}
+		bandSummaries = append(bandSummaries, historyBandSummary{
+			MinAge:      int64(i) * bandWidth,
+			MaxAge:      min(int64(i+1)*bandWidth, versions) - 1,
+			Queries:     band.queries,
+			Unavailable: band.unavailable,
+			SuccessRate: band.successRate(),
+			Latency:     band.latency.summary(false),
+		})
+	}
+
+	attrs := []any{
+		"distribution", w.params.Distribution,
+		"first_version", firstVersion,
+		"latest_version", latestVersion,
+		"queries", total.queries,
+		"unavailable", total.unavailable,
+		"found", total.found,
+		"not_found", total.queries - total.unavailable - total.found,
+		"success_rate", total.successRate(),
+		"latency", total.latency.summary(true),
+		"by_age", bandSummaries,
+	}
+	if total.lastErr != nil {
+		attrs = append(attrs, "last_error", total.lastErr.Error())
+	}
+	logger.Info("historical query stats", attrs...)
+}
@@ -0,0 +1,198 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	storev1beta1 "cosmossdk.io/api/cosmos/store/v1beta1"
+)
+
+// Formats of recorded chain state changes which ImportChangesets reads.
+const (
+	// ImportFormatADR038 is the output of the ADR-038 file streaming service of the Cosmos SDK v0.47:
+	// one "[prefix-]block-<height>-data" file per block holding the big-endian uint64 length of the rest
+	// of the file followed by the length-prefixed StoreKVPairs written during the block.
+	ImportFormatADR038 = "adr038"
+	// ImportFormatDelimited is one file per block of varint length-delimited StoreKVPairs, e.g. the change
+	// sets passed to an ABCIListener's ListenCommit marshaled with protodelim. The height of a block is the
+	// last number in the name of its file.
+	ImportFormatDelimited = "delimited"
+)
+
+var (
+	adr038DataFileRegexp = regexp.MustCompile(`^(?:.+-)?block-(\d+)-data$`)
+	lastNumberRegexp     = regexp.MustCompile(`(\d+)\D*$`)
+)
+
+// ImportParams are the parameters for importing changesets from state changes recorded by a chain.
+type ImportParams struct {
+	// Format is the format of the files in InputDir, ImportFormatADR038 or ImportFormatDelimited.
+	Format string
+	// InputDir is the dir holding the files of the recorded blocks.
+	InputDir string
+	// StartHeight and EndHeight optionally restrict the import to the blocks in [StartHeight, EndHeight],
+	// a value of 0 leaves the range unbounded on that side.
+	StartHeight int64
+	EndHeight   int64
+	// Stores optionally restricts the import to the changes of these stores.
+	Stores []string
+}
+
+type blockFile struct {
+	height int64
+	path   string
+}
+
+// ImportChangesets writes the state changes recorded by a chain for consecutive blocks as changesets to outDir,
+// the first imported block becoming version 1. Unless the import starts at the first block of the chain, the
+// changesets only hold the changes since then and not the state from before, which the runs start without.
+func ImportChangesets(p ImportParams, outDir string) error {
+	blocks, err := listBlockFiles(p)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("no blocks found in %s", p.InputDir)
+	}
+	for i := 1; i < len(blocks); i++ {
+		if missing := blocks[i-1].height + 1; blocks[i].height != missing {
+			return fmt.Errorf("block %d is missing from %s", missing, p.InputDir)
+		}
+	}
+
+	var stores map[string]bool
+	if len(p.Stores) != 0 {
+		stores = make(map[string]bool, len(p.Stores))
+		for _, store := range p.Stores {
+			stores[store] = true
+		}
+	}
+
+	source := fmt.Sprintf("%s:%s", p.Format, p.InputDir)
+	if abs, err := filepath.Abs(p.InputDir); err == nil {
+		source = fmt.Sprintf("%s:%s", p.Format, abs)
+	}
+	w, err := NewChangesetWriter(outDir, source, blocks[0].height)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for _, block := range blocks {
+		version := w.Version()
+		n, err := importBlock(w, p.Format, block.path, stores)
+		if err != nil {
+			return fmt.Errorf("error importing block %d: %w", block.height, err)
+		}
+		err = w.Commit()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d changes of block %d from %s as version %d\n", n, block.height, block.path, version)
+	}
+	return nil
+}
+
+// listBlockFiles returns the files of the blocks in the height range of p ordered by height.
+func listBlockFiles(p ImportParams) ([]blockFile, error) {
+	var nameRegexp *regexp.Regexp
+	switch p.Format {
+	case ImportFormatADR038:
+		nameRegexp = adr038DataFileRegexp
+	case ImportFormatDelimited:
+		nameRegexp = lastNumberRegexp
+	default:
+		return nil, fmt.Errorf("unknown import format %q, expected %s or %s", p.Format, ImportFormatADR038, ImportFormatDelimited)
+	}
+
+	entries, err := os.ReadDir(p.InputDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input dir: %w", err)
+	}
+	var res []blockFile
+	paths := map[int64]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := nameRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		height, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing height of %s: %w", entry.Name(), err)
+		}
+		if height < p.StartHeight || (p.EndHeight != 0 && height > p.EndHeight) {
+			continue
+		}
+		path := filepath.Join(p.InputDir, entry.Name())
+		if other, ok := paths[height]; ok {
+			return nil, fmt.Errorf("both %s and %s hold block %d", other, path, height)
+		}
+		paths[height] = path
+		res = append(res, blockFile{height: height, path: path})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].height < res[j].height
+	})
+	return res, nil
+}
+
+// importBlock writes the changes of the stores of a block to the current version of w, returning their number.
+// A nil stores imports all stores.
+func importBlock(w *ChangesetWriter, format, path string, stores map[string]bool) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if format == ImportFormatADR038 {
+		stat, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		var size uint64
+		err = binary.Read(f, binary.BigEndian, &size)
+		if err != nil {
+			return 0, fmt.Errorf("error reading length prefix of %s: %w", path, err)
+		}
+		// the streaming service writes the prefix before the data, a crash can leave the data incomplete
+		if size != uint64(stat.Size()-8) {
+			return 0, fmt.Errorf("%s holds %d bytes of data but its length prefix is %d", path, stat.Size()-8, size)
+		}
+	}
+	buffered := bufio.NewReader(f)
+
+	n := 0
+	for i := 0; ; i++ {
+		var pair storev1beta1.StoreKVPair
+		err := changesetUnmarshalOptions.UnmarshalFrom(buffered, &pair)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("error at entry %d reading %s: %w", i, path, err)
+		}
+		if stores != nil && !stores[pair.StoreKey] {
+			continue
+		}
+		if pair.Delete {
+			// the value of a delete is meaningless, only the key is kept
+			pair.Value = nil
+		}
+		err = w.Write(&pair)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
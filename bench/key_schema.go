@@ -0,0 +1,167 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Types of the segments of a key schema.
+const (
+	// KeySegmentFixed is the fixed bytes Hex, e.g. the prefix of a collection.
+	KeySegmentFixed = "fixed"
+	// KeySegmentAddress is one of Population addresses of Length bytes, 20 by default.
+	KeySegmentAddress = "address"
+	// KeySegmentChoice is one of Values, e.g. a denom.
+	KeySegmentChoice = "choice"
+	// KeySegmentCounter is a big-endian counter of Length bytes, 8 by default, which increases with every key.
+	KeySegmentCounter = "counter"
+	// KeySegmentHeight is the big-endian version the key is created at in Length bytes, 8 by default.
+	KeySegmentHeight = "height"
+	// KeySegmentRandom is Length random bytes.
+	KeySegmentRandom = "random"
+)
+
+// maxKeyAttempts is the number of keys generated from a key schema in search of one which doesn't exist yet
+// before giving up, because a schema with small populations can only generate a limited number of keys.
+const maxKeyAttempts = 1000
+
+// KeySegment is a part of the keys generated from a key schema, see StoreParams.KeySchema.
+type KeySegment struct {
+	// Type is one of KeySegmentFixed, KeySegmentAddress, KeySegmentChoice, KeySegmentCounter,
+	// KeySegmentHeight or KeySegmentRandom.
+	Type string `json:"type"`
+	// Hex is the hex encoded bytes of a KeySegmentFixed.
+	Hex string `json:"hex,omitempty"`
+	// Values are the values of a KeySegmentChoice.
+	Values []string `json:"values,omitempty"`
+	// Population is the number of distinct addresses of a KeySegmentAddress.
+	Population int `json:"population,omitempty"`
+	// Length is the length in bytes of a KeySegmentAddress, KeySegmentCounter, KeySegmentHeight or KeySegmentRandom.
+	Length int `json:"length,omitempty"`
+	// LengthPrefixed prefixes the segment with its length in one byte, like addresses in Cosmos SDK keys.
+	LengthPrefixed bool `json:"length_prefixed,omitempty"`
+}
+
+// keySchema generates keys from the segments of StoreParams.KeySchema.
+type keySchema struct {
+	storeKey string
+	segments []KeySegment
+	// fixed are the decoded bytes of the KeySegmentFixed segments by index
+	fixed map[int][]byte
+	// counters are the next values of the KeySegmentCounter segments by index
+	counters map[int]uint64
+}
+
+func newKeySchema(storeKey string, segments []KeySegment) (*keySchema, error) {
+	s := &keySchema{storeKey: storeKey, fixed: map[int][]byte{}, counters: map[int]uint64{}}
+	for i, seg := range segments {
+		if seg.Length < 0 {
+			return nil, fmt.Errorf("key segment %d: length must not be negative", i)
+		}
+		defaultLength := 0
+		switch seg.Type {
+		case KeySegmentFixed:
+			bz, err := hex.DecodeString(seg.Hex)
+			if err != nil {
+				return nil, fmt.Errorf("key segment %d: invalid hex: %w", i, err)
+			}
+			s.fixed[i] = bz
+		case KeySegmentAddress:
+			if seg.Population < 1 {
+				return nil, fmt.Errorf("key segment %d: population must be positive", i)
+			}
+			defaultLength = 20
+		case KeySegmentChoice:
+			if len(seg.Values) == 0 {
+				return nil, fmt.Errorf("key segment %d: values must not be empty", i)
+			}
+		case KeySegmentCounter, KeySegmentHeight:
+			defaultLength = 8
+		case KeySegmentRandom:
+			if seg.Length < 1 {
+				return nil, fmt.Errorf("key segment %d: length must be positive", i)
+			}
+		default:
+			return nil, fmt.Errorf("key segment %d: unknown type %q", i, seg.Type)
+		}
+		if seg.Length == 0 {
+			seg.Length = defaultLength
+		}
+		switch {
+		case seg.Type == KeySegmentAddress && seg.Length > sha256.Size:
+			return nil, fmt.Errorf("key segment %d: addresses can't be longer than %d bytes", i, sha256.Size)
+		case (seg.Type == KeySegmentCounter || seg.Type == KeySegmentHeight) && seg.Length > 8:
+			return nil, fmt.Errorf("key segment %d: %s can't be longer than 8 bytes", i, seg.Type)
+		case seg.LengthPrefixed && seg.maxLength(s.fixed[i]) > 255:
+			return nil, fmt.Errorf("key segment %d: length-prefixed segments can't be longer than 255 bytes", i)
+		}
+		s.segments = append(s.segments, seg)
+	}
+	if len(s.segments) == 0 {
+		return nil, fmt.Errorf("key schema has no segments")
+	}
+	return s, nil
+}
+
+// gen generates a key for a create at version.
+func (s *keySchema) gen(rng *rand.Rand, version int64) []byte {
+	var key []byte
+	for i, seg := range s.segments {
+		var bz []byte
+		switch seg.Type {
+		case KeySegmentFixed:
+			bz = s.fixed[i]
+		case KeySegmentAddress:
+			bz = s.address(i, rng.IntN(seg.Population), seg.Length)
+		case KeySegmentChoice:
+			bz = []byte(seg.Values[rng.IntN(len(seg.Values))])
+		case KeySegmentCounter:
+			bz = bigEndian(s.counters[i], seg.Length)
+			s.counters[i]++
+		case KeySegmentHeight:
+			bz = bigEndian(uint64(version), seg.Length)
+		case KeySegmentRandom:
+			bz = make([]byte, seg.Length)
+			for j := range bz {
+				bz[j] = byte(rng.IntN(256))
+			}
+		}
+		if seg.LengthPrefixed {
+			key = append(key, byte(len(bz)))
+		}
+		key = append(key, bz...)
+	}
+	return key
+}
+
+// maxLength returns the length of the longest value of the segment, whose fixed bytes are given for a KeySegmentFixed.
+func (seg KeySegment) maxLength(fixed []byte) int {
+	switch seg.Type {
+	case KeySegmentFixed:
+		return len(fixed)
+	case KeySegmentChoice:
+		res := 0
+		for _, v := range seg.Values {
+			res = max(res, len(v))
+		}
+		return res
+	default:
+		return seg.Length
+	}
+}
+
+// address returns the address of index i in the population of segment, which is the same in every run.
+func (s *keySchema) address(segment, i, length int) []byte {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%d", s.storeKey, segment, i)))
+	return hash[:length]
+}
+
+// bigEndian returns the lowest length bytes of v in big-endian order.
+func bigEndian(v uint64, length int) []byte {
+	var bz [8]byte
+	binary.BigEndian.PutUint64(bz[:], v)
+	return bz[8-length:]
+}
@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestKeySchemaValidation(t *testing.T) {
+	long := strings.Repeat("x", 256)
+	tests := []struct {
+		name     string
+		segments []KeySegment
+		wantErr  bool
+	}{
+		{name: "no segments", wantErr: true},
+		{name: "unknown type", segments: []KeySegment{{Type: "uuid"}}, wantErr: true},
+		{name: "negative length", segments: []KeySegment{{Type: KeySegmentCounter, Length: -1}}, wantErr: true},
+		{name: "fixed", segments: []KeySegment{{Type: KeySegmentFixed, Hex: "0a0b"}}},
+		{name: "fixed invalid hex", segments: []KeySegment{{Type: KeySegmentFixed, Hex: "0x0a"}}, wantErr: true},
+		{name: "fixed prefixed 255 bytes", segments: []KeySegment{{Type: KeySegmentFixed, Hex: strings.Repeat("ab", 255), LengthPrefixed: true}}},
+		{name: "fixed prefixed 256 bytes", segments: []KeySegment{{Type: KeySegmentFixed, Hex: strings.Repeat("ab", 256), LengthPrefixed: true}}, wantErr: true},
+		{name: "fixed 256 bytes", segments: []KeySegment{{Type: KeySegmentFixed, Hex: strings.Repeat("ab", 256)}}},
+		{name: "address", segments: []KeySegment{{Type: KeySegmentAddress, Population: 1}}},
+		{name: "address without population", segments: []KeySegment{{Type: KeySegmentAddress}}, wantErr: true},
+		{name: "address of 32 bytes", segments: []KeySegment{{Type: KeySegmentAddress, Population: 1, Length: 32}}},
+		{name: "address of 33 bytes", segments: []KeySegment{{Type: KeySegmentAddress, Population: 1, Length: 33}}, wantErr: true},
+		{name: "choice", segments: []KeySegment{{Type: KeySegmentChoice, Values: []string{"a"}}}},
+		{name: "choice without values", segments: []KeySegment{{Type: KeySegmentChoice}}, wantErr: true},
+		{name: "choice prefixed 256 bytes", segments: []KeySegment{{Type: KeySegmentChoice, Values: []string{"a", long}, LengthPrefixed: true}}, wantErr: true},
+		{name: "counter of 8 bytes", segments: []KeySegment{{Type: KeySegmentCounter, Length: 8}}},
+		{name: "counter of 9 bytes", segments: []KeySegment{{Type: KeySegmentCounter, Length: 9}}, wantErr: true},
+		{name: "height of 1 byte", segments: []KeySegment{{Type: KeySegmentHeight, Length: 1}}},
+		{name: "height of 9 bytes", segments: []KeySegment{{Type: KeySegmentHeight, Length: 9}}, wantErr: true},
+		{name: "random without length", segments: []KeySegment{{Type: KeySegmentRandom}}, wantErr: true},
+		{name: "random prefixed 255 bytes", segments: []KeySegment{{Type: KeySegmentRandom, Length: 255, LengthPrefixed: true}}},
+		{name: "random prefixed 256 bytes", segments: []KeySegment{{Type: KeySegmentRandom, Length: 256, LengthPrefixed: true}}, wantErr: true},
+		{
+			name:     "invalid second segment",
+			segments: []KeySegment{{Type: KeySegmentFixed, Hex: "01"}, {Type: KeySegmentRandom}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newKeySchema("bank", tt.segments)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newKeySchema() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeySchemaGen(t *testing.T) {
+	// like the balances of the bank module: prefix, length-prefixed address and denom
+	s, err := newKeySchema("bank", []KeySegment{
+		{Type: KeySegmentFixed, Hex: "02"},
+		{Type: KeySegmentAddress, Population: 3, LengthPrefixed: true},
+		{Type: KeySegmentChoice, Values: []string{"uatom", "uosmo"}},
+		{Type: KeySegmentCounter, Length: 2},
+		{Type: KeySegmentHeight},
+		{Type: KeySegmentCounter},
+		{Type: KeySegmentRandom, Length: 4, LengthPrefixed: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := map[string]bool{}
+	for i := range 3 {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("bank/1/%d", i)))
+		addresses[string(hash[:20])] = true
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range 20 {
+		version := int64(100 + i/5)
+		key := s.gen(rng, version)
+		if len(key) != 1+1+20+5+2+8+8+1+4 {
+			t.Fatalf("key %X has length %d", key, len(key))
+		}
+		if key[0] != 0x02 || key[1] != 20 {
+			t.Errorf("key %X doesn't start with the prefix and address length", key)
+		}
+		if !addresses[string(key[2:22])] {
+			t.Errorf("key %X has an address outside the population", key)
+		}
+		if denom := string(key[22:27]); denom != "uatom" && denom != "uosmo" {
+			t.Errorf("key %X has denom %q", key, denom)
+		}
+		// each counter segment counts on its own
+		if want := bigEndian(uint64(i), 2); !bytes.Equal(key[27:29], want) {
+			t.Errorf("key %X has counter %X, want %X", key, key[27:29], want)
+		}
+		if want := bigEndian(uint64(version), 8); !bytes.Equal(key[29:37], want) {
+			t.Errorf("key %X has height %X, want %X", key, key[29:37], want)
+		}
+		if want := bigEndian(uint64(i), 8); !bytes.Equal(key[37:45], want) {
+			t.Errorf("key %X has second counter %X, want %X", key, key[37:45], want)
+		}
+		if key[45] != 4 {
+			t.Errorf("key %X has random length prefix %d", key, key[45])
+		}
+	}
+}
+
+func TestBigEndian(t *testing.T) {
+	tests := []struct {
+		v      uint64
+		length int
+		want   []byte
+	}{
+		{v: 0x0102, length: 8, want: []byte{0, 0, 0, 0, 0, 0, 1, 2}},
+		{v: 0x0102, length: 2, want: []byte{1, 2}},
+		// the counter wraps around in short segments
+		{v: 0x0102, length: 1, want: []byte{2}},
+		{v: 0x0102, length: 0, want: []byte{}},
+	}
+	for _, tt := range tests {
+		if got := bigEndian(tt.v, tt.length); !bytes.Equal(got, tt.want) {
+			t.Errorf("bigEndian(%#x, %d) = %X, want %X", tt.v, tt.length, got, tt.want)
+		}
+	}
+}
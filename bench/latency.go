@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// latencyBucketsPerDoubling is the number of histogram buckets for every doubling of latency,
+	// giving each bucket a width of about 19%.
+	latencyBucketsPerDoubling = 4
+	// numLatencyBuckets covers latencies up to 2^48ns (about 78 hours).
+	numLatencyBuckets = 48 * latencyBucketsPerDoubling
+)
+
+// latencyHistogram records durations into exponentially sized buckets.
+// It is not safe for concurrent use.
+type latencyHistogram struct {
+	counts [numLatencyBuckets]uint64
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func latencyBucketIndex(d time.Duration) int {
+	if d <= 1 {
+		return 0
+	}
+	idx := int(math.Log2(float64(d)) * latencyBucketsPerDoubling)
+	if idx >= numLatencyBuckets {
+		return numLatencyBuckets - 1
+	}
+	return idx
+}
+
+func latencyBucketUpperBound(idx int) time.Duration {
+	return time.Duration(math.Exp2(float64(idx+1) / latencyBucketsPerDoubling))
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[latencyBucketIndex(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// merge adds all observations of other to h.
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	if other.count == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+func (h *latencyHistogram) reset() {
+	*h = latencyHistogram{}
+}
+
+// percentile returns the upper bound of the bucket containing the q-th quantile, capped at the maximum observed value.
+// The last bucket also holds all longer durations, so its quantiles are the maximum.
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(h.count)))
+	if target == 0 {
+		target = 1
+	}
+	var cumulative uint64
+	for i, c := range h.counts[:numLatencyBuckets-1] {
+		cumulative += c
+		if cumulative >= target {
+			return min(latencyBucketUpperBound(i), h.max)
+		}
+	}
+	return h.max
+}
+
+type latencyBucket struct {
+	// LE is the inclusive upper bound of the bucket.
+	LE    time.Duration `json:"le"`
+	Count uint64        `json:"count"`
+}
+
+// latencySummary is the loggable form of a latencyHistogram. Durations are logged in nanoseconds.
+type latencySummary struct {
+	Count   uint64          `json:"count"`
+	Mean    time.Duration   `json:"mean"`
+	Min     time.Duration   `json:"min"`
+	Max     time.Duration   `json:"max"`
+	P50     time.Duration   `json:"p50"`
+	P90     time.Duration   `json:"p90"`
+	P99     time.Duration   `json:"p99"`
+	P999    time.Duration   `json:"p999"`
+	Buckets []latencyBucket `json:"buckets,omitempty"`
+}
+
+// summary returns the summary statistics of h, including the non-empty buckets if withBuckets is true.
+func (h *latencyHistogram) summary(withBuckets bool) latencySummary {
+	s := latencySummary{
+		Count: h.count,
+		Min:   h.min,
+		Max:   h.max,
+		P50:   h.percentile(0.5),
+		P90:   h.percentile(0.9),
+		P99:   h.percentile(0.99),
+		P999:  h.percentile(0.999),
+	}
+	if h.count > 0 {
+		s.Mean = h.sum / time.Duration(h.count)
+	}
+	if withBuckets {
+		for i, c := range h.counts {
+			if c > 0 {
+				s.Buckets = append(s.Buckets, latencyBucket{LE: latencyBucketUpperBound(i), Count: c})
+			}
+		}
+	}
+	return s
+}
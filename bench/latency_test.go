@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBucketIndex(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{d: 0, want: 0},
+		{d: 1, want: 0},
+		{d: 2, want: 4},
+		{d: 1023, want: 39},
+		// a power of two is the lower bound of its bucket
+		{d: 1024, want: 40},
+		{d: 1217, want: 40},
+		{d: 1218, want: 41},
+		{d: 1 << 47, want: 188},
+		{d: 1<<48 - 1, want: numLatencyBuckets - 1},
+		{d: 100 * time.Hour, want: numLatencyBuckets - 1},
+	}
+	for _, tt := range tests {
+		if got := latencyBucketIndex(tt.d); got != tt.want {
+			t.Errorf("latencyBucketIndex(%d) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	repeat := func(d time.Duration, n int) []time.Duration {
+		ds := make([]time.Duration, n)
+		for i := range ds {
+			ds[i] = d
+		}
+		return ds
+	}
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		q       float64
+		want    time.Duration
+	}{
+		{
+			name: "empty",
+			q:    0.5,
+			want: 0,
+		},
+		{
+			name:    "zero duration",
+			samples: []time.Duration{0},
+			q:       0.5,
+			want:    0,
+		},
+		{
+			name:    "single sample capped at max",
+			samples: []time.Duration{1000},
+			q:       0.5,
+			want:    1000,
+		},
+		{
+			name:    "zero quantile is the first sample",
+			samples: []time.Duration{1000, 2000},
+			q:       0,
+			want:    1024,
+		},
+		{
+			name:    "below a bucket edge",
+			samples: []time.Duration{1023, 1024, 2000},
+			q:       1.0 / 3,
+			want:    1024,
+		},
+		{
+			name:    "on a bucket edge",
+			samples: []time.Duration{1023, 1024, 2000},
+			q:       0.5,
+			want:    1217,
+		},
+		{
+			name:    "last bucket capped at max",
+			samples: []time.Duration{1023, 1024, 2000},
+			q:       1,
+			want:    2000,
+		},
+		{
+			name:    "rank exactly at the end of a bucket",
+			samples: append(repeat(1024, 90), repeat(time.Millisecond, 10)...),
+			q:       0.9,
+			want:    1217,
+		},
+		{
+			name:    "rank one past the end of a bucket",
+			samples: append(repeat(1024, 90), repeat(time.Millisecond, 10)...),
+			q:       0.91,
+			want:    time.Millisecond,
+		},
+		{
+			name:    "rank rounded up",
+			samples: append(repeat(1024, 99), repeat(time.Millisecond, 1)...),
+			q:       0.999,
+			want:    time.Millisecond,
+		},
+		{
+			name:    "overflow bucket is the max",
+			samples: []time.Duration{time.Second, 100 * time.Hour},
+			q:       0.99,
+			want:    100 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h latencyHistogram
+			for _, d := range tt.samples {
+				h.record(d)
+			}
+			if got := h.percentile(tt.q); got != tt.want {
+				t.Errorf("percentile(%g) = %d, want %d", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	var a, b, all latencyHistogram
+	for i, d := range []time.Duration{5, 3000, 70, time.Millisecond, 1, 900} {
+		if i%2 == 0 {
+			a.record(d)
+		} else {
+			b.record(d)
+		}
+		all.record(d)
+	}
+	var empty latencyHistogram
+	a.merge(&empty)
+	a.merge(&b)
+	if a != all {
+		t.Errorf("merged histogram = %+v, want %+v", a.summary(true), all.summary(true))
+	}
+}
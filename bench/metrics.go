@@ -0,0 +1,113 @@
+package bench
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// runMetrics are the Prometheus metrics of a run. They are registered with the default registry,
+// which also exports Go runtime and process stats as well as any metrics registered by the tree.
+// A nil *runMetrics disables metrics.
+type runMetrics struct {
+	version         prometheus.Gauge
+	versionsApplied prometheus.Counter
+	changesApplied  prometheus.Counter
+	opsPerSec       prometheus.Gauge
+	commitLatency   prometheus.Histogram
+	dbDirSize       prometheus.Gauge
+}
+
+func newRunMetrics(treeType string) (*runMetrics, error) {
+	labels := prometheus.Labels{"tree_type": treeType}
+	m := &runMetrics{
+		version: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "iavl_bench_version",
+			Help:        "Last committed version.",
+			ConstLabels: labels,
+		}),
+		versionsApplied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "iavl_bench_versions_applied_total",
+			Help:        "Number of versions applied and committed by this run.",
+			ConstLabels: labels,
+		}),
+		changesApplied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "iavl_bench_changes_applied_total",
+			Help:        "Number of sets and deletes applied by this run.",
+			ConstLabels: labels,
+		}),
+		opsPerSec: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "iavl_bench_ops_per_second",
+			Help:        "Changes applied per second over the last committed version, including its commit.",
+			ConstLabels: labels,
+		}),
+		commitLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "iavl_bench_commit_duration_seconds",
+			Help:        "Duration of committing a version.",
+			ConstLabels: labels,
+			// 1ms to ~9m
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 20),
+		}),
+		dbDirSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "iavl_bench_db_dir_size_bytes",
+			Help:        "Total size of the files in the db dir.",
+			ConstLabels: labels,
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.version, m.versionsApplied, m.changesApplied, m.opsPerSec, m.commitLatency, m.dbDirSize} {
+		err := prometheus.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// serveMetrics serves the default registry at /metrics on addr until the returned server is closed.
+func serveMetrics(logger *slog.Logger, addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", "error", err)
+		}
+	}()
+	logger.Info("serving metrics", "addr", listener.Addr().String())
+	return server, nil
+}
+
+func (m *runMetrics) setVersion(version int64) {
+	if m == nil {
+		return
+	}
+	m.version.Set(float64(version))
+}
+
+func (m *runMetrics) observeCommit(version int64, changes int, opsPerSec float64, commitDuration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.version.Set(float64(version))
+	m.versionsApplied.Inc()
+	m.changesApplied.Add(float64(changes))
+	m.opsPerSec.Set(opsPerSec)
+	m.commitLatency.Observe(commitDuration.Seconds())
+}
+
+func (m *runMetrics) setDBDirSize(size uint64) {
+	if m == nil {
+		return
+	}
+	m.dbDirSize.Set(float64(size))
+}
@@ -0,0 +1,224 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// Profile types which can be captured during a run.
+const (
+	ProfileCPU   = "cpu"
+	ProfileHeap  = "heap"
+	ProfileMutex = "mutex"
+	ProfileBlock = "block"
+)
+
+// ProfileParams configure the profiles captured to files during a run.
+type ProfileParams struct {
+	// Dir is the directory profiles are written to. Empty disables profile capture.
+	Dir string
+	// Interval is the number of versions covered by each set of profiles.
+	Interval int64
+	// Types are the profiles to capture, any of ProfileCPU, ProfileHeap, ProfileMutex and ProfileBlock.
+	Types []string
+	// MutexFraction is passed to runtime.SetMutexProfileFraction when mutex profiles are captured.
+	MutexFraction int
+	// BlockRate is passed to runtime.SetBlockProfileRate when block profiles are captured.
+	BlockRate int
+}
+
+func (p ProfileParams) validate() error {
+	if p.Dir == "" {
+		return nil
+	}
+	if p.Interval <= 0 {
+		return fmt.Errorf("profile-interval must be positive")
+	}
+	for _, typ := range p.Types {
+		switch typ {
+		case ProfileCPU, ProfileHeap, ProfileMutex, ProfileBlock:
+		default:
+			return fmt.Errorf("unknown profile type %q, expected any of %s, %s, %s or %s",
+				typ, ProfileCPU, ProfileHeap, ProfileMutex, ProfileBlock)
+		}
+	}
+	return nil
+}
+
+// profiler captures profiles for consecutive ranges of versions. The CPU profile covers exactly the
+// versions in its file name, while heap, mutex and block profiles are snapshots taken after the last
+// version of the range. Mutex and block profiles are cumulative since the start of the process, so the
+// profile of an earlier range can be passed to `go tool pprof -base` to isolate a single range.
+// A nil *profiler disables profile capture.
+type profiler struct {
+	params ProfileParams
+	logger *slog.Logger
+	// from is the first version of the current range, 0 if no range is in progress
+	from    int64
+	cpuFile *os.File
+	cpu     bool
+	heap    bool
+	mutex   bool
+	block   bool
+}
+
+func newProfiler(logger *slog.Logger, params ProfileParams) (*profiler, error) {
+	err := params.validate()
+	if err != nil {
+		return nil, err
+	}
+	if params.Dir == "" {
+		return nil, nil
+	}
+	err = os.MkdirAll(params.Dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating profile dir: %w", err)
+	}
+	p := &profiler{params: params, logger: logger}
+	for _, typ := range params.Types {
+		switch typ {
+		case ProfileCPU:
+			p.cpu = true
+		case ProfileHeap:
+			p.heap = true
+		case ProfileMutex:
+			p.mutex = true
+		case ProfileBlock:
+			p.block = true
+		}
+	}
+	if p.mutex {
+		runtime.SetMutexProfileFraction(params.MutexFraction)
+	}
+	if p.block {
+		runtime.SetBlockProfileRate(params.BlockRate)
+	}
+	return p, nil
+}
+
+func (p *profiler) filename(typ string, from, to int64) string {
+	return filepath.Join(p.params.Dir, fmt.Sprintf("%s-%08d-%08d.pprof", typ, from, to))
+}
+
+// beforeVersion starts a new range of profiles at version unless one is already in progress.
+func (p *profiler) beforeVersion(version int64) error {
+	if p == nil || p.from != 0 {
+		return nil
+	}
+	p.from = version
+	if !p.cpu {
+		return nil
+	}
+	// the end of the range is not known yet, so the file is renamed when the range ends
+	file, err := os.Create(filepath.Join(p.params.Dir, fmt.Sprintf("cpu-%08d-partial.pprof", version)))
+	if err != nil {
+		return fmt.Errorf("error creating cpu profile: %w", err)
+	}
+	err = runtimepprof.StartCPUProfile(file)
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error starting cpu profile: %w", err)
+	}
+	p.cpuFile = file
+	return nil
+}
+
+// afterVersion ends the current range if it covers the profile interval.
+func (p *profiler) afterVersion(version int64) error {
+	if p == nil || version-p.from+1 < p.params.Interval {
+		return nil
+	}
+	return p.stop(version)
+}
+
+// stop ends the current range at version, the last version applied, writing all of its profiles.
+func (p *profiler) stop(version int64) error {
+	if p == nil || p.from == 0 {
+		return nil
+	}
+	from := p.from
+	p.from = 0
+	if version < from {
+		// no version of the range was applied
+		version = from
+	}
+
+	var errs []error
+	if p.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		partial := p.cpuFile.Name()
+		err := p.cpuFile.Close()
+		p.cpuFile = nil
+		if err == nil {
+			err = os.Rename(partial, p.filename(ProfileCPU, from, version))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error writing cpu profile: %w", err))
+		}
+	}
+	if p.heap {
+		// collect garbage so that the in-use heap is up to date
+		runtime.GC()
+		errs = append(errs, p.writeProfile(ProfileHeap, from, version))
+	}
+	if p.mutex {
+		errs = append(errs, p.writeProfile(ProfileMutex, from, version))
+	}
+	if p.block {
+		errs = append(errs, p.writeProfile(ProfileBlock, from, version))
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		return err
+	}
+	p.logger.Info("captured profiles", "from_version", from, "to_version", version, "dir", p.params.Dir)
+	return nil
+}
+
+func (p *profiler) writeProfile(typ string, from, to int64) error {
+	file, err := os.Create(p.filename(typ, from, to))
+	if err != nil {
+		return fmt.Errorf("error creating %s profile: %w", typ, err)
+	}
+	err = runtimepprof.Lookup(typ).WriteTo(file, 0)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s profile: %w", typ, err)
+	}
+	return nil
+}
+
+// servePprof serves the net/http/pprof handlers at /debug/pprof/ on addr until the returned server is closed.
+func servePprof(logger *slog.Logger, addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("pprof server failed", "error", err)
+		}
+	}()
+	logger.Info("serving pprof", "addr", listener.Addr().String())
+	return server, nil
+}
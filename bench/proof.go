@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	ics23 "github.com/cosmos/ics23/go"
+)
+
+// Prover is an optional interface which a Tree can implement to support proof generation.
+// Proofs must verify against the store hashes returned by Commit using the IAVL ICS23 spec.
+type Prover interface {
+	// GetProof returns an ICS23 proof for key in the store at a previously committed version:
+	// an existence proof if the key exists and a non-existence proof otherwise.
+	// It should return an error if the version is not available.
+	GetProof(storeKey string, version int64, key []byte) (*ics23.CommitmentProof, error)
+}
+
+// ProofParams configure the proofs generated after each commit.
+type ProofParams struct {
+	// Count is the number of proofs generated per version. Zero disables proofs.
+	Count int
+	// MissRatio is the fraction of proofs for keys which are not expected to exist, i.e. non-existence proofs.
+	MissRatio float64
+}
+
+// proofWorkload generates proofs for sampled keys after each commit, like a relayer querying for
+// IBC packet commitments, and verifies them against the committed store hashes.
+type proofWorkload struct {
+	prover Prover
+	params ProofParams
+	rng    *rand.Rand
+	keys   *keySamples
+}
+
+func newProofWorkload(tree Tree, params ProofParams) (*proofWorkload, error) {
+	prover, ok := tree.(Prover)
+	if !ok {
+		return nil, fmt.Errorf("tree of type %T does not implement Prover", tree)
+	}
+	rng := rand.New(rand.NewPCG(4, 0))
+	return &proofWorkload{
+		prover: prover,
+		params: params,
+		rng:    rng,
+		keys:   newKeySamples(rng),
+	}, nil
+}
+
+func (w *proofWorkload) afterUpdate(storeKey string, key, value []byte, delete bool) {
+	w.keys.update(storeKey, key, value, delete)
+}
+
+// verifyProof checks proof for key against the store root hash. value is the expected value of key, nil if it should not exist.
+func verifyProof(root []byte, proof *ics23.CommitmentProof, key, value []byte) error {
+	if value != nil {
+		if proof.GetExist() == nil {
+			return fmt.Errorf("expected an existence proof")
+		}
+		if !ics23.VerifyMembership(ics23.IavlSpec, root, proof, key, value) {
+			return fmt.Errorf("existence proof does not verify against root %X", root)
+		}
+		return nil
+	}
+	if proof.GetNonexist() == nil {
+		return fmt.Errorf("expected a non-existence proof")
+	}
+	if !ics23.VerifyNonMembership(ics23.IavlSpec, root, proof, key) {
+		return fmt.Errorf("non-existence proof does not verify against root %X", root)
+	}
+	return nil
+}
+
+// afterCommit generates and verifies the proofs of the committed version and logs their stats.
+// Proofs which cannot be generated or fail to verify are counted and logged, but do not stop the run.
+func (w *proofWorkload) afterCommit(logger *slog.Logger, version int64, commitInfo CommitInfo) {
+	if w.keys.empty() {
+		return
+	}
+	var generateLatency, verifyLatency latencyHistogram
+	existence, nonExistence, generateErrors, verifyFailures, proofBytes := 0, 0, 0, 0, 0
+	var lastErr error
+	for i := 0; i < w.params.Count; i++ {
+		miss := w.rng.Float64() < w.params.MissRatio
+		storeKey, key := w.keys.random(miss)
+		value := w.keys.value(storeKey, key)
+
+		start := time.Now()
+		proof, err := w.prover.GetProof(storeKey, version, key)
+		if err != nil {
+			generateErrors++
+			lastErr = fmt.Errorf("generating proof for key %X in store %s: %w", key, storeKey, err)
+			continue
+		}
+		generateLatency.record(time.Since(start))
+		proofBytes += proof.Size()
+		if proof.GetExist() != nil {
+			existence++
+		} else {
+			nonExistence++
+		}
+
+		start = time.Now()
+		err = verifyProof(commitInfo.StoreHashes[storeKey], proof, key, value)
+		verifyLatency.record(time.Since(start))
+		if err != nil {
+			verifyFailures++
+			lastErr = fmt.Errorf("proof for key %X in store %s: %w", key, storeKey, err)
+		}
+	}
+
+	attrs := []any{
+		"version", version,
+		"proofs", w.params.Count,
+		"existence", existence,
+		"non_existence", nonExistence,
+		"generate_errors", generateErrors,
+		"verify_failures", verifyFailures,
+		"mean_proof_bytes", proofBytes / max(existence+nonExistence, 1),
+		"generate_latency", generateLatency.summary(true),
+		"verify_latency", verifyLatency.summary(false),
+	}
+	if lastErr != nil {
+		attrs = append(attrs, "last_error", lastErr.Error())
+		logger.Warn("proof stats", attrs...)
+	} else {
+		logger.Info("proof stats", attrs...)
+	}
+}
@@ -0,0 +1,293 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueryParams configure the concurrent queries issued against the last committed version
+// while the next version is being applied and committed.
+type QueryParams struct {
+	// Workers is the number of concurrent query goroutines. Zero disables queries.
+	Workers int
+	// IterFraction is the fraction of queries which are range iterations instead of point reads.
+	IterFraction float64
+	// IterLimit is the maximum number of entries read by each range iteration.
+	IterLimit int
+}
+
+// querySnapshotSize is the number of keys per store sampled for the queries against each committed version.
+const querySnapshotSize = 10_000
+
+type sampledKV struct {
+	key []byte
+	// value is the value of key at the sampled version, nil if the key does not exist
+	value []byte
+}
+
+// querySnapshot is an immutable set of keys and their expected values at a committed version.
+type querySnapshot struct {
+	version int64
+	stores  []string
+	kvs     map[string][]sampledKV
+}
+
+// snapshot samples up to n keys per store with their current values.
+func (k *keySamples) snapshot(version int64, n int) *querySnapshot {
+	snap := &querySnapshot{
+		version: version,
+		stores:  append([]string(nil), k.names...),
+		kvs:     make(map[string][]sampledKV, len(k.names)),
+	}
+	for _, name := range k.names {
+		sample := k.stores[name]
+		kvs := make([]sampledKV, min(n, len(sample.keys)))
+		for i := range kvs {
+			j := k.rng.IntN(len(sample.keys))
+			kvs[i] = sampledKV{key: sample.keys[j], value: sample.values[j]}
+		}
+		snap.kvs[name] = kvs
+	}
+	return snap
+}
+
+func (s *querySnapshot) random(rng *rand.Rand) (string, sampledKV) {
+	storeKey := s.stores[rng.IntN(len(s.stores))]
+	kvs := s.kvs[storeKey]
+	return storeKey, kvs[rng.IntN(len(kvs))]
+}
+
+// commitPhase is the phase of applying a version which a query overlapped with.
+type commitPhase int32
+
+const (
+	phaseApply commitPhase = iota
+	phaseCommit
+	numPhases
+)
+
+// queryStats are the stats collected by query workers since they were last reset.
+type queryStats struct {
+	getLatency  [numPhases]latencyHistogram
+	iterLatency [numPhases]latencyHistogram
+	iterItems   int
+	mismatches  int
+	errors      int
+	lastErr     error
+	// lastMismatch describes the last get which didn't return the expected value
+	lastMismatch error
+}
+
+func (s *queryStats) merge(other *queryStats) {
+	for phase := range numPhases {
+		s.getLatency[phase].merge(&other.getLatency[phase])
+		s.iterLatency[phase].merge(&other.iterLatency[phase])
+	}
+	s.iterItems += other.iterItems
+	s.mismatches += other.mismatches
+	s.errors += other.errors
+	if other.lastErr != nil {
+		s.lastErr = other.lastErr
+	}
+	if other.lastMismatch != nil {
+		s.lastMismatch = other.lastMismatch
+	}
+}
+
+// workerStats are the stats of a single query worker, guarded by a mutex so they can be collected after each commit.
+type workerStats struct {
+	mtx sync.Mutex
+	queryStats
+}
+
+// queryLoad runs query workers against the last committed version while the writer applies the next one.
+type queryLoad struct {
+	reader   Reader
+	iterable Iterable
+	// serialized is true if the tree serializes the queries with the writes, see ConcurrentReader
+	serialized bool
+	params     QueryParams
+	keys       *keySamples
+	snapshot   atomic.Pointer[querySnapshot]
+	phase      atomic.Int32
+	stats      []*workerStats
+	closeCh    chan struct{}
+	wg         sync.WaitGroup
+}
+
+func newQueryLoad(tree Tree, params QueryParams) (*queryLoad, error) {
+	reader, ok := tree.(Reader)
+	if !ok {
+		return nil, fmt.Errorf("tree of type %T does not implement Reader", tree)
+	}
+	q := &queryLoad{
+		reader:  reader,
+		params:  params,
+		keys:    newKeySamples(rand.New(rand.NewPCG(1, 0))),
+		closeCh: make(chan struct{}),
+	}
+	if params.IterFraction > 0 {
+		q.iterable, ok = tree.(Iterable)
+		if !ok {
+			return nil, fmt.Errorf("tree of type %T does not implement Iterable", tree)
+		}
+	}
+	if concurrent, ok := tree.(ConcurrentReader); ok {
+		q.serialized = concurrent.EnableConcurrentReads()
+	}
+	for i := 0; i < params.Workers; i++ {
+		stats := &workerStats{}
+		q.stats = append(q.stats, stats)
+		q.wg.Add(1)
+		go q.runWorker(stats, rand.New(rand.NewPCG(uint64(i), 1)))
+	}
+	return q, nil
+}
+
+func (q *queryLoad) close() {
+	close(q.closeCh)
+	q.wg.Wait()
+}
+
+func (q *queryLoad) runWorker(stats *workerStats, rng *rand.Rand) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.closeCh:
+			return
+		default:
+		}
+
+		snap := q.snapshot.Load()
+		if snap == nil || len(snap.stores) == 0 {
+			// nothing has been committed yet
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		if q.iterable != nil && rng.Float64() < q.params.IterFraction {
+			q.iterate(stats, rng, snap)
+		} else {
+			q.get(stats, rng, snap)
+		}
+	}
+}
+
+func (q *queryLoad) get(stats *workerStats, rng *rand.Rand, snap *querySnapshot) {
+	storeKey, kv := snap.random(rng)
+	phase := commitPhase(q.phase.Load())
+	start := time.Now()
+	value, err := q.reader.GetAt(storeKey, snap.version, kv.key)
+	duration := time.Since(start)
+
+	stats.mtx.Lock()
+	defer stats.mtx.Unlock()
+	stats.getLatency[phase].record(duration)
+	if err != nil {
+		stats.errors++
+		stats.lastErr = fmt.Errorf("get of key %X in store %s at version %d: %w", kv.key, storeKey, snap.version, err)
+	} else if !bytes.Equal(value, kv.value) || (value == nil) != (kv.value == nil) {
+		stats.mismatches++
+		stats.lastMismatch = fmt.Errorf("get of key %X in store %s at version %d returned %X, expected %X",
+			kv.key, storeKey, snap.version, value, kv.value)
+	}
+}
+
+func (q *queryLoad) iterate(stats *workerStats, rng *rand.Rand, snap *querySnapshot) {
+	storeKey, kv := snap.random(rng)
+	// iterate away from a known key in a random direction
+	ascending := rng.IntN(2) == 0
+	start, end := kv.key, []byte(nil)
+	if !ascending {
+		start, end = nil, kv.key
+	}
+	phase := commitPhase(q.phase.Load())
+	begin := time.Now()
+	items, err := q.iterateRange(storeKey, snap.version, start, end, ascending)
+	duration := time.Since(begin)
+
+	stats.mtx.Lock()
+	defer stats.mtx.Unlock()
+	stats.iterLatency[phase].record(duration)
+	stats.iterItems += items
+	if err != nil {
+		stats.errors++
+		stats.lastErr = fmt.Errorf("iteration from key %X in store %s at version %d: %w", kv.key, storeKey, snap.version, err)
+	}
+}
+
+func (q *queryLoad) iterateRange(storeKey string, version int64, start, end []byte, ascending bool) (int, error) {
+	itr, err := q.iterable.IteratorAt(storeKey, version, start, end, ascending)
+	if err != nil {
+		return 0, err
+	}
+	return drainIterator(itr, q.params.IterLimit)
+}
+
+func (q *queryLoad) afterUpdate(storeKey string, key, value []byte, delete bool) {
+	q.keys.update(storeKey, key, value, delete)
+}
+
+func (q *queryLoad) beforeCommit() {
+	q.phase.Store(int32(phaseCommit))
+}
+
+// afterCommit logs the stats of the queries which ran while version was applied and committed,
+// then points the workers at the newly committed version.
+func (q *queryLoad) afterCommit(logger *slog.Logger, version int64) {
+	var total queryStats
+	for _, stats := range q.stats {
+		stats.mtx.Lock()
+		total.merge(&stats.queryStats)
+		stats.queryStats = queryStats{}
+		stats.mtx.Unlock()
+	}
+
+	var getLatency, iterLatency latencyHistogram
+	for phase := range numPhases {
+		getLatency.merge(&total.getLatency[phase])
+		iterLatency.merge(&total.iterLatency[phase])
+	}
+	queriedVersion := int64(0)
+	if snap := q.snapshot.Load(); snap != nil {
+		queriedVersion = snap.version
+	}
+	attrs := []any{
+		"version", version,
+		"queried_version", queriedVersion,
+		"gets", getLatency.count,
+		"iterations", iterLatency.count,
+		"iter_items", total.iterItems,
+		"mismatches", total.mismatches,
+		"errors", total.errors,
+		"get_latency", getLatency.summary(false),
+		"get_latency_apply", total.getLatency[phaseApply].summary(false),
+		"get_latency_commit", total.getLatency[phaseCommit].summary(false),
+		"iter_latency", iterLatency.summary(false),
+		"iter_latency_apply", total.iterLatency[phaseApply].summary(false),
+		"iter_latency_commit", total.iterLatency[phaseCommit].summary(false),
+	}
+	if q.serialized {
+		// the latencies include waiting for the writes, they aren't comparable with trees reading concurrently
+		attrs = append(attrs, "reads_serialized", true)
+	}
+	if total.lastErr != nil {
+		attrs = append(attrs, "last_error", total.lastErr.Error())
+	}
+	if total.lastMismatch != nil {
+		attrs = append(attrs, "last_mismatch", total.lastMismatch.Error())
+	}
+	if total.errors > 0 || total.mismatches > 0 {
+		logger.Warn("query stats", attrs...)
+	} else {
+		logger.Info("query stats", attrs...)
+	}
+
+	q.snapshot.Store(q.keys.snapshot(version, querySnapshotSize))
+	q.phase.Store(int32(phaseApply))
+}
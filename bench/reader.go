@@ -0,0 +1,240 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// Reader is an optional interface which a Tree can implement to support read workloads.
+// GetAt and HasAt must be safe to call concurrently with ApplyUpdate and Commit for the last committed version.
+type Reader interface {
+	// Get returns the value of key in the working state of the store, including uncommitted changes,
+	// or nil if the key does not exist.
+	Get(storeKey string, key []byte) ([]byte, error)
+	// Has returns whether key exists in the working state of the store, including uncommitted changes.
+	Has(storeKey string, key []byte) (bool, error)
+	// GetAt returns the value of key in the store at a previously committed version, or nil if the key does not exist.
+	// It should return an error if the version is not available.
+	GetAt(storeKey string, version int64, key []byte) ([]byte, error)
+	// HasAt returns whether key exists in the store at a previously committed version.
+	// It should return an error if the version is not available.
+	HasAt(storeKey string, version int64, key []byte) (bool, error)
+}
+
+// ConcurrentReader is an optional interface for a Reader which has to prepare for GetAt, HasAt and IteratorAt
+// being called concurrently with ApplyUpdate and Commit, so that it doesn't pay for it when no queries run.
+// EnableConcurrentReads is called before the first version is applied if concurrent queries are configured.
+// It returns true if the tree serializes the reads with the writes instead of running them concurrently.
+type ConcurrentReader interface {
+	EnableConcurrentReads() (serialized bool)
+}
+
+// Iterator iterates over a range of keys, see Iterable.
+type Iterator interface {
+	Valid() bool
+	Next()
+	Key() []byte
+	Value() []byte
+	Error() error
+	Close() error
+}
+
+// Iterable is an optional interface which a Tree can implement to support range iteration.
+// Iteration covers keys in [start, end), a nil start or end leaves the range unbounded on that side.
+// IteratorAt must be safe to call concurrently with ApplyUpdate and Commit for the last committed version.
+type Iterable interface {
+	// Iterator iterates over the working state of the store, including uncommitted changes.
+	Iterator(storeKey string, start, end []byte, ascending bool) (Iterator, error)
+	// IteratorAt iterates over the store at a previously committed version.
+	// It should return an error if the version is not available.
+	IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (Iterator, error)
+}
+
+// maxSampledKeys is the maximum number of keys per store remembered for generating reads.
+const maxSampledKeys = 100_000
+
+// keySample is a uniform reservoir sample of keys which have been written to a store,
+// together with the latest value written to each of them.
+type keySample struct {
+	keys [][]byte
+	// values are the latest values of keys, nil if the key has since been deleted
+	values [][]byte
+	index  map[string]int
+	seen   int64
+}
+
+func (s *keySample) set(rng *rand.Rand, key, value []byte) {
+	if i, ok := s.index[string(key)]; ok {
+		s.values[i] = value
+		return
+	}
+	s.seen++
+	if len(s.keys) < maxSampledKeys {
+		s.index[string(key)] = len(s.keys)
+		s.keys = append(s.keys, key)
+		s.values = append(s.values, value)
+		return
+	}
+	if j := rng.Int64N(s.seen); j < maxSampledKeys {
+		delete(s.index, string(s.keys[j]))
+		s.index[string(key)] = int(j)
+		s.keys[j] = key
+		s.values[j] = value
+	}
+}
+
+func (s *keySample) delete(key []byte) {
+	if i, ok := s.index[string(key)]; ok {
+		s.values[i] = nil
+	}
+}
+
+func (s *keySample) random(rng *rand.Rand) []byte {
+	return s.keys[rng.IntN(len(s.keys))]
+}
+
+// keySamples tracks a keySample for every store.
+type keySamples struct {
+	rng    *rand.Rand
+	stores map[string]*keySample
+	// names are the stores with at least one sampled key, in sorted order so that selection is deterministic
+	names []string
+}
+
+func newKeySamples(rng *rand.Rand) *keySamples {
+	return &keySamples{rng: rng, stores: map[string]*keySample{}}
+}
+
+// update records an applied set or delete.
+func (k *keySamples) update(storeKey string, key, value []byte, delete bool) {
+	sample, ok := k.stores[storeKey]
+	if delete {
+		if ok {
+			sample.delete(key)
+		}
+		return
+	}
+	if !ok {
+		sample = &keySample{index: map[string]int{}}
+		k.stores[storeKey] = sample
+		k.names = append(k.names, storeKey)
+		sort.Strings(k.names)
+	}
+	sample.set(k.rng, key, value)
+}
+
+func (k *keySamples) empty() bool {
+	return len(k.names) == 0
+}
+
+// random selects a store uniformly and then a sampled key of that store uniformly.
+// If miss is true, a byte is appended to the key so that it most likely does not exist but still sorts between existing keys.
+func (k *keySamples) random(miss bool) (string, []byte) {
+	storeKey := k.names[k.rng.IntN(len(k.names))]
+	key := k.stores[storeKey].random(k.rng)
+	if miss {
+		key = append(bytes.Clone(key), byte(k.rng.IntN(256)))
+	}
+	return storeKey, key
+}
+
+// value returns the latest value of a sampled key, nil if it has been deleted or is not sampled.
+func (k *keySamples) value(storeKey string, key []byte) []byte {
+	sample, ok := k.stores[storeKey]
+	if !ok {
+		return nil
+	}
+	i, ok := sample.index[string(key)]
+	if !ok {
+		return nil
+	}
+	return sample.values[i]
+}
+
+// ReadParams configure the point reads interleaved with the writes of each version.
+type ReadParams struct {
+	// Ratio is the number of reads issued per applied change. Zero disables reads.
+	Ratio float64
+	// MissRatio is the fraction of reads which target keys that are not expected to exist.
+	MissRatio float64
+}
+
+// readWorkload issues point reads of keys known to exist in the changesets while they are being applied.
+type readWorkload struct {
+	reader      Reader
+	params      ReadParams
+	rng         *rand.Rand
+	keys        *keySamples
+	accumulator float64
+
+	// per version stats
+	latency  latencyHistogram
+	found    int
+	notFound int
+}
+
+func newReadWorkload(tree Tree, params ReadParams) (*readWorkload, error) {
+	reader, ok := tree.(Reader)
+	if !ok {
+		return nil, fmt.Errorf("tree of type %T does not implement Reader", tree)
+	}
+	rng := rand.New(rand.NewPCG(0, 0))
+	return &readWorkload{
+		reader: reader,
+		params: params,
+		rng:    rng,
+		keys:   newKeySamples(rng),
+	}, nil
+}
+
+// afterUpdate records an applied update and issues the reads which are due according to the read ratio.
+func (w *readWorkload) afterUpdate(storeKey string, key, value []byte, delete bool) error {
+	w.keys.update(storeKey, key, value, delete)
+	if w.keys.empty() {
+		return nil
+	}
+	w.accumulator += w.params.Ratio
+	for w.accumulator >= 1 {
+		w.accumulator--
+		err := w.read()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *readWorkload) read() error {
+	miss := w.rng.Float64() < w.params.MissRatio
+	storeKey, key := w.keys.random(miss)
+	start := time.Now()
+	value, err := w.reader.Get(storeKey, key)
+	w.latency.record(time.Since(start))
+	if err != nil {
+		return fmt.Errorf("error reading key %X from store %s: %w", key, storeKey, err)
+	}
+	if value != nil {
+		w.found++
+	} else {
+		w.notFound++
+	}
+	return nil
+}
+
+// logVersion logs the read stats of the version and resets them.
+func (w *readWorkload) logVersion(logger *slog.Logger, version int64) {
+	logger.Info("read stats",
+		"version", version,
+		"reads", w.found+w.notFound,
+		"found", w.found,
+		"not_found", w.notFound,
+		"latency", w.latency.summary(true),
+	)
+	w.latency.reset()
+	w.found = 0
+	w.notFound = 0
+}
@@ -0,0 +1,295 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// RunSummary summarizes the jsonl log of a bench run. It computes the same statistics as the summary
+// function of analysis/analysis.py, plus the RSS and commit latency which that doesn't report.
+type RunSummary struct {
+	// Name is the log file name without the .jsonl extension, i.e. the run name of an iavl-bench-all plan.
+	Name string `json:"name"`
+	// Complete is true if the log records the completion of the run.
+	Complete        bool `json:"complete"`
+	VersionsApplied int  `json:"versions_applied"`
+	// Elapsed is the time from the start of the run to its completion, or to its last committed version
+	// if it didn't complete. For resumed runs it includes the time the run was stopped.
+	Elapsed time.Duration `json:"elapsed"`
+	// OpsPerSec is the number of changes applied divided by the total duration of all versions.
+	OpsPerSec float64 `json:"ops_per_sec"`
+	// MaxMem is the maximum sampled heap allocation of the Go runtime in bytes.
+	MaxMem uint64 `json:"max_mem"`
+	// MaxRSS is the maximum sampled resident set size of the runner in bytes, 0 for logs which don't record it.
+	MaxRSS uint64 `json:"max_rss"`
+	// MaxDisk is the maximum sampled size of the db dir in bytes.
+	MaxDisk uint64 `json:"max_disk"`
+	// CommitLatency is nil for logs which don't record commit durations.
+	CommitLatency *CommitPercentiles `json:"commit_latency,omitempty"`
+}
+
+// CommitPercentiles are the exact percentiles of the commit durations of all versions of a run.
+type CommitPercentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// logEntry holds the fields of the log entries which are summarized.
+// Sizes are humanized strings, durations are in nanoseconds.
+type logEntry struct {
+	Time           string         `json:"time"`
+	Msg            string         `json:"msg"`
+	Version        int64          `json:"version"`
+	ResumedVersion int64          `json:"resumed_version"`
+	Duration       time.Duration  `json:"duration"`
+	CommitDuration *time.Duration `json:"commit_duration"`
+	Count          int64          `json:"count"`
+	Alloc          string         `json:"alloc"`
+	RSS            string         `json:"rss"`
+	Size           string         `json:"size"`
+	// DiskUsage is only logged with committed versions by old runners
+	DiskUsage string `json:"disk_usage"`
+	// MemStats is only logged by old runners
+	MemStats *struct {
+		Alloc uint64 `json:"Alloc"`
+	} `json:"mem_stats"`
+}
+
+type versionEntry struct {
+	version        int64
+	time           string
+	duration       time.Duration
+	commitDuration *time.Duration
+	count          int64
+}
+
+type sizeEntry struct {
+	version int64
+	size    uint64
+}
+
+// runLog is the data of a run log which is needed for its summary.
+type runLog struct {
+	startTime    string
+	completeTime string
+	versions     []versionEntry
+	mem          []sizeEntry
+	rss          []sizeEntry
+	disk         []sizeEntry
+}
+
+func readRunLog(path string) (*runLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := &runLog{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Msg string `json:"msg"`
+		}
+		// lines which aren't log entries, e.g. a line cut off by a crash, are skipped
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil {
+			continue
+		}
+		switch msg.Msg {
+		case "starting run", "resumed run", "benchmark run complete", "committed version", "mem stats",
+			"disk usage", "full post-commit stats":
+		default:
+			// other entries, in particular those of the trees, may use the same keys for other types
+			continue
+		}
+		var entry logEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %q entry: %w", msg.Msg, err)
+		}
+		err = res.add(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q entry: %w", msg.Msg, err)
+		}
+	}
+	return res, scanner.Err()
+}
+
+func (l *runLog) add(entry *logEntry) error {
+	switch entry.Msg {
+	case "starting run":
+		// a resumed run logs another 'starting run', the first one describes the whole run
+		if l.startTime == "" {
+			l.startTime = entry.Time
+		}
+	case "resumed run":
+		// versions after the resumed version were not persisted and are applied again by the resumed run
+		l.truncate(entry.ResumedVersion)
+	case "benchmark run complete":
+		l.completeTime = entry.Time
+	case "committed version":
+		l.versions = append(l.versions, versionEntry{
+			version:        entry.Version,
+			time:           entry.Time,
+			duration:       entry.Duration,
+			commitDuration: entry.CommitDuration,
+			count:          entry.Count,
+		})
+		if entry.DiskUsage != "" {
+			return l.addSize(&l.disk, entry.Version, entry.DiskUsage)
+		}
+	case "mem stats":
+		err := l.addSize(&l.mem, entry.Version, entry.Alloc)
+		if err != nil || entry.RSS == "" {
+			return err
+		}
+		return l.addSize(&l.rss, entry.Version, entry.RSS)
+	case "disk usage":
+		return l.addSize(&l.disk, entry.Version, entry.Size)
+	case "full post-commit stats":
+		if entry.MemStats != nil {
+			l.mem = append(l.mem, sizeEntry{version: entry.Version, size: entry.MemStats.Alloc})
+		}
+	}
+	return nil
+}
+
+func (l *runLog) addSize(entries *[]sizeEntry, version int64, humanized string) error {
+	size, err := humanize.ParseBytes(humanized)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, sizeEntry{version: version, size: size})
+	return nil
+}
+
+func (l *runLog) truncate(version int64) {
+	l.versions = slices.DeleteFunc(l.versions, func(e versionEntry) bool { return e.version > version })
+	for _, entries := range []*[]sizeEntry{&l.mem, &l.rss, &l.disk} {
+		*entries = slices.DeleteFunc(*entries, func(e sizeEntry) bool { return e.version > version })
+	}
+}
+
+func maxSize(entries []sizeEntry) uint64 {
+	var res uint64
+	for _, e := range entries {
+		res = max(res, e.size)
+	}
+	return res
+}
+
+func (l *runLog) summary(name string) (RunSummary, error) {
+	s := RunSummary{
+		Name:            name,
+		Complete:        l.completeTime != "",
+		VersionsApplied: len(l.versions),
+		MaxMem:          maxSize(l.mem),
+		MaxRSS:          maxSize(l.rss),
+		MaxDisk:         maxSize(l.disk),
+	}
+
+	var count int64
+	var duration time.Duration
+	var commitDurations []time.Duration
+	for _, v := range l.versions {
+		count += v.count
+		duration += v.duration
+		if v.commitDuration != nil {
+			commitDurations = append(commitDurations, *v.commitDuration)
+		}
+	}
+	if duration > 0 {
+		s.OpsPerSec = float64(count) / duration.Seconds()
+	}
+	// logs of old runners don't record commit durations
+	if len(commitDurations) > 0 && len(commitDurations) == len(l.versions) {
+		slices.Sort(commitDurations)
+		s.CommitLatency = &CommitPercentiles{
+			P50: nearestRank(commitDurations, 0.5),
+			P90: nearestRank(commitDurations, 0.9),
+			P99: nearestRank(commitDurations, 0.99),
+			Max: commitDurations[len(commitDurations)-1],
+		}
+	}
+
+	endTime := l.completeTime
+	if endTime == "" && len(l.versions) > 0 {
+		endTime = l.versions[len(l.versions)-1].time
+	}
+	if l.startTime != "" && endTime != "" {
+		start, err := time.Parse(time.RFC3339Nano, l.startTime)
+		if err != nil {
+			return RunSummary{}, fmt.Errorf("error parsing start time: %w", err)
+		}
+		end, err := time.Parse(time.RFC3339Nano, endTime)
+		if err != nil {
+			return RunSummary{}, fmt.Errorf("error parsing end time: %w", err)
+		}
+		s.Elapsed = end.Sub(start)
+	}
+	return s, nil
+}
+
+// SummarizeRunLog summarizes the jsonl log of a bench run written with --log-type json.
+func SummarizeRunLog(path string) (RunSummary, error) {
+	l, err := readRunLog(path)
+	if err != nil {
+		return RunSummary{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	s, err := l.summary(strings.TrimSuffix(filepath.Base(path), ".jsonl"))
+	if err != nil {
+		return RunSummary{}, fmt.Errorf("error summarizing %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// SummarizeRuns summarizes the run logs at the given paths, which are either log files or directories
+// of runs written by iavl-bench-all. The summaries are sorted by name.
+func SummarizeRuns(paths ...string) ([]RunSummary, error) {
+	var logFiles []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			logFiles = append(logFiles, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			// hash logs of the verify command aren't run logs
+			if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".hashes.jsonl") {
+				continue
+			}
+			logFiles = append(logFiles, filepath.Join(path, name))
+		}
+	}
+
+	res := make([]RunSummary, 0, len(logFiles))
+	for _, logFile := range logFiles {
+		s, err := SummarizeRunLog(logFile)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
@@ -0,0 +1,107 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// summaries of the logs in testdata/runs, computed by hand
+var (
+	// version 3 of the first run and its mem stats are replaced by those of the resumed run,
+	// the line cut off by the crash is skipped
+	resumedSummary = RunSummary{
+		Name:            "resumed",
+		Complete:        true,
+		VersionsApplied: 4,
+		Elapsed:         65 * time.Second,
+		// 6000 changes in 6s
+		OpsPerSec: 1000,
+		MaxMem:    150_000_000,
+		MaxRSS:    300_000_000,
+		MaxDisk:   50_000_000,
+		CommitLatency: &CommitPercentiles{
+			P50: 200 * time.Millisecond,
+			P90: 400 * time.Millisecond,
+			P99: 400 * time.Millisecond,
+			Max: 400 * time.Millisecond,
+		},
+	}
+	// an old runner's log which is cut off in the middle of its last line
+	cutOffSummary = RunSummary{
+		Name:            "cut-off",
+		VersionsApplied: 2,
+		Elapsed:         5 * time.Second,
+		// 2500 changes in 5s
+		OpsPerSec: 500,
+		MaxMem:    1234,
+		MaxDisk:   20_000_000,
+	}
+)
+
+func TestSummarizeRunLog(t *testing.T) {
+	tests := []struct {
+		path string
+		want RunSummary
+	}{
+		{path: "testdata/runs/resumed.jsonl", want: resumedSummary},
+		{path: "testdata/runs/cut-off.jsonl", want: cutOffSummary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := SummarizeRunLog(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SummarizeRunLog() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeRuns(t *testing.T) {
+	// the hash log in the dir is skipped and the summaries are sorted by name
+	got, err := SummarizeRuns("testdata/runs/resumed.jsonl", "testdata/runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RunSummary{cutOffSummary, resumedSummary, resumedSummary}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeRuns() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeRunLogErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{
+			name: "bad size",
+			log:  `{"time":"2024-01-01T00:00:00Z","msg":"mem stats","version":1,"alloc":"lots"}`,
+		},
+		{
+			name: "bad field type",
+			log:  `{"time":"2024-01-01T00:00:00Z","msg":"committed version","version":"one"}`,
+		},
+		{
+			name: "bad time",
+			log: `{"time":"yesterday","msg":"starting run"}
+{"time":"2024-01-01T00:00:00Z","msg":"benchmark run complete"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run.jsonl")
+			if err := os.WriteFile(path, []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := SummarizeRunLog(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package bench
 import (
 	"bufio"
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -21,8 +22,8 @@ import (
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/host"
 	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
 	"github.com/spf13/cobra"
-	"google.golang.org/protobuf/encoding/protodelim"
 )
 
 // Tree is a generic interface wrapping a multi-store tree structure.
@@ -31,8 +32,8 @@ type Tree interface {
 	Version() int64
 	// ApplyUpdate should apply a single set or delete to the tree.
 	ApplyUpdate(storeKey string, key, value []byte, delete bool) error
-	// Commit should persist all changes made since the last commit and return the new version's hash.
-	Commit() error
+	// Commit should persist all changes made since the last commit and return the new version's store hashes.
+	Commit() (CommitInfo, error)
 	io.Closer
 }
 
@@ -67,121 +68,300 @@ func (r Runner) Run() {
 }
 
 func NewRunner(treeType string, cfg RunConfig) Runner {
-	var treeDir string
-	var treeOptions string
-	var changesetDir string
-	var targetVersion int64
-	var logHandlerType string
-	var logFile string
+	var flags runFlags
+	var reads ReadParams
+	var queries QueryParams
+	var scans ScanParams
+	var history HistoryParams
+	var proofs ProofParams
+	var profiles ProfileParams
+	var commitStats CommitStatsParams
+	var metricsAddr string
+	var pprofAddr string
 	cmd := &cobra.Command{
 		Use:   "bench",
 		Short: "Runs benchmarks for the tree implementation.",
 	}
-	cmd.Flags().StringVar(&treeDir, "db-dir", "", "Directory for the db's data.")
-	cmd.Flags().StringVar(&treeOptions, "db-options", "", "Implementation specific options for the db, in JSON format.")
-	cmd.Flags().StringVar(&changesetDir, "changeset-dir", "", "Directory containing the changeset files.")
-	cmd.Flags().Int64Var(&targetVersion, "target-version", 0, "Target version to apply changesets up to. If this is empty or 0, all remaining versions in the changeset-dir will be applied.")
-	cmd.Flags().StringVar(&logHandlerType, "log-type", "text", "Log handler type. One of 'text' or 'json'.")
-	cmd.Flags().StringVar(&logFile, "log-file", "", "If set, log output will be written to this file instead of stdout.")
+	flags.register(cmd)
+	cmd.Flags().Float64Var(&reads.Ratio, "read-ratio", 0, "Number of point reads to interleave per applied change, e.g. 0.5 for one read every two changes. Requires the tree to implement Reader.")
+	cmd.Flags().Float64Var(&reads.MissRatio, "read-miss-ratio", 0.1, "Fraction of point reads which target keys that are not expected to exist.")
+	cmd.Flags().IntVar(&queries.Workers, "query-workers", 0, "Number of goroutines querying the last committed version while the next version is applied and committed. Requires the tree to implement Reader. Trees which serialize the queries with the writes, like iavl/v2, log reads_serialized with the query stats.")
+	cmd.Flags().Float64Var(&queries.IterFraction, "query-iter-fraction", 0.1, "Fraction of concurrent queries which are range iterations rather than point reads. Requires the tree to implement Iterable.")
+	cmd.Flags().IntVar(&queries.IterLimit, "query-iter-limit", 100, "Maximum number of entries read by each concurrent range iteration.")
+	cmd.Flags().IntVar(&scans.Count, "scan-count", 0, "Number of prefix scans to perform after each commit. Requires the tree to implement Iterable.")
+	cmd.Flags().IntVar(&scans.PrefixLen, "scan-prefix-len", 1, "Length in bytes of the key prefix scanned, taken from a key known to exist.")
+	cmd.Flags().IntVar(&scans.Width, "scan-width", 100, "Maximum number of entries read by each prefix scan.")
+	cmd.Flags().IntVar(&history.Queries, "history-queries", 0, "Number of point reads at past versions to perform after all changesets have been applied. Requires the tree to implement Reader.")
+	cmd.Flags().StringVar(&history.Distribution, "history-distribution", HistoryUniform, "Distribution of the versions queried by history-queries. One of 'uniform', 'recent' or 'old'.")
+	cmd.Flags().IntVar(&proofs.Count, "proof-count", 0, "Number of ICS23 proofs to generate and verify after each commit. Requires the tree to implement Prover.")
+	cmd.Flags().Float64Var(&proofs.MissRatio, "proof-miss-ratio", 0.1, "Fraction of proofs for keys which are not expected to exist, i.e. non-existence proofs.")
+	cmd.Flags().IntVar(&commitStats.Window, "commit-stats-window", 100, "Number of most recent versions covered by each rolling commit latency summary.")
+	cmd.Flags().IntVar(&commitStats.Interval, "commit-stats-interval", 10, "Number of versions between rolling commit latency summaries. Zero disables them.")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "If set, the address to serve Prometheus metrics on at /metrics, e.g. ':9090'.")
+	cmd.Flags().StringVar(&pprofAddr, "pprof-addr", "", "If set, the address to serve net/http/pprof on at /debug/pprof/, e.g. 'localhost:6060'.")
+	cmd.Flags().StringVar(&profiles.Dir, "profile-dir", "", "If set, the directory to periodically write CPU, heap, mutex and block profiles to, named by the range of versions they cover.")
+	cmd.Flags().Int64Var(&profiles.Interval, "profile-interval", 100, "Number of versions covered by each set of profiles written to profile-dir.")
+	cmd.Flags().StringSliceVar(&profiles.Types, "profile-types", []string{ProfileCPU, ProfileHeap, ProfileMutex, ProfileBlock}, "Profiles to write to profile-dir. Any of 'cpu', 'heap', 'mutex' and 'block'.")
+	cmd.Flags().IntVar(&profiles.MutexFraction, "mutex-profile-fraction", 100, "On average 1/n mutex contention events are recorded when mutex profiles are written.")
+	cmd.Flags().IntVar(&profiles.BlockRate, "block-profile-rate", 1_000_000, "On average one blocking event per n nanoseconds blocked is recorded when block profiles are written.")
+	cmd.Flags().BoolVar(&flags.resume, "resume", false, "Continue a previous run from the last version committed to db-dir, appending to the existing log file instead of truncating it.")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if treeDir == "" {
-			return fmt.Errorf("tree-dir is required")
+		loaded, err := flags.load(treeType, cfg)
+		if err != nil {
+			return err
 		}
-
-		if changesetDir == "" {
-			return fmt.Errorf("changeset-dir is required")
+		defer loaded.closeLog()
+
+		loaded.params.Reads = reads
+		loaded.params.Queries = queries
+		loaded.params.Scans = scans
+		loaded.params.History = history
+		loaded.params.Proofs = proofs
+		loaded.params.CommitStats = commitStats
+		if metricsAddr != "" {
+			loaded.params.Metrics, err = newRunMetrics(treeType)
+			if err != nil {
+				return fmt.Errorf("error registering metrics: %w", err)
+			}
+			server, err := serveMetrics(loaded.params.Logger, metricsAddr)
+			if err != nil {
+				return fmt.Errorf("error serving metrics: %w", err)
+			}
+			defer server.Close()
 		}
-
-		changesetInfo, err := readChangesetInfo(changesetDir)
+		if pprofAddr != "" {
+			server, err := servePprof(loaded.params.Logger, pprofAddr)
+			if err != nil {
+				return fmt.Errorf("error serving pprof: %w", err)
+			}
+			defer server.Close()
+		}
+		loaded.params.Profiler, err = newProfiler(loaded.params.Logger, profiles)
 		if err != nil {
-			return fmt.Errorf("error reading changeset info file: %w", err)
+			return err
 		}
+		return run(loaded.tree, flags.changesetDir, loaded.changesetInfo, loaded.params)
+	}
 
-		if targetVersion <= 0 {
-			targetVersion = changesetInfo.Versions
-		}
+	rootCmd := &cobra.Command{}
+	rootCmd.AddCommand(cmd, newVerifyCommand(treeType, cfg), newCrashCommand(treeType, cfg))
+	return Runner{Command: rootCmd}
+}
 
-		// decode db options from json
-		var opts interface{}
-		if cfg.OptionsType != nil {
-			opts = reflect.New(reflect.TypeOf(cfg.OptionsType).Elem()).Interface()
-			if treeOptions != "" {
-				if cfg.OptionsType == nil {
-					return fmt.Errorf("db-options provided but no OptionsType set in RunConfig")
-				}
-				decoder := json.NewDecoder(bytes.NewReader([]byte(treeOptions)))
-				// we disallow unknown fields to catch typos with database options
-				decoder.DisallowUnknownFields()
-				err := decoder.Decode(opts)
-				if err != nil {
-					return fmt.Errorf("error unmarshaling db-options: %w", err)
-				}
-			}
+func newVerifyCommand(treeType string, cfg RunConfig) *cobra.Command {
+	var flags runFlags
+	var hashesFile string
+	var referenceHashes string
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Replays changesets and records the root hashes of each version, optionally checking them against a reference hash log.",
+	}
+	flags.register(cmd)
+	cmd.Flags().StringVar(&hashesFile, "hashes-file", "", "File to write the jsonl hash log of each committed version to.")
+	cmd.Flags().StringVar(&referenceHashes, "reference-hashes", "", "If set, a hash log produced by another runner to compare against. The run stops at the first divergent version.")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if hashesFile == "" {
+			return fmt.Errorf("hashes-file is required")
 		}
 
-		logOut := os.Stdout
-		if logFile != "" {
-			logOut, err = os.Create(logFile)
+		hashesOut, err := os.Create(hashesFile)
+		if err != nil {
+			return fmt.Errorf("error creating hashes file: %w", err)
+		}
+		defer func() {
+			err := hashesOut.Close()
 			if err != nil {
-				return fmt.Errorf("error creating log file: %w", err)
+				slog.Error("error closing hashes file", "error", err)
 			}
-			defer func() {
-				err := logOut.Close()
-				if err != nil {
-					slog.Error("error closing log file", "error", err)
-				}
-			}()
-		}
+		}()
 
-		var handler slog.Handler
-		switch logHandlerType {
-		case "text":
-			handler = slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})
-		case "json":
-			handler = slog.NewJSONHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})
-		default:
-			return fmt.Errorf("unknown log handler type: %s", logHandlerType)
+		verifier, err := newHashVerifier(hashesOut, referenceHashes)
+		if err != nil {
+			return fmt.Errorf("error loading reference hashes: %w", err)
 		}
 
-		// Create a separate handler for tree logger at info level
-		var treeHandler slog.Handler
-		switch logHandlerType {
-		case "text":
-			treeHandler = slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelInfo})
-		case "json":
-			treeHandler = slog.NewJSONHandler(logOut, &slog.HandlerOptions{Level: slog.LevelInfo, AddSource: true})
-		default:
-			return fmt.Errorf("unknown log handler type: %s", logHandlerType)
+		loaded, err := flags.load(treeType, cfg)
+		if err != nil {
+			return err
 		}
+		defer loaded.closeLog()
 
-		logger := slog.New(handler).With("module", "runner")
-		treeLogger := slog.New(treeHandler)
-		logger.Info("Starting benchmark run, loading tree")
+		loaded.params.Verifier = verifier
+		return run(loaded.tree, flags.changesetDir, loaded.changesetInfo, loaded.params)
+	}
+	return cmd
+}
+
+// runFlags are the flags shared by all commands which load a tree and apply changesets to it.
+type runFlags struct {
+	treeDir        string
+	treeOptions    string
+	changesetDir   string
+	targetVersion  int64
+	logHandlerType string
+	logFile        string
+	// resume is only registered by the bench command
+	resume bool
+}
+
+func (f *runFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.treeDir, "db-dir", "", "Directory for the db's data.")
+	cmd.Flags().StringVar(&f.treeOptions, "db-options", "", "Implementation specific options for the db, in JSON format.")
+	cmd.Flags().StringVar(&f.changesetDir, "changeset-dir", "", "Directory containing the changeset files.")
+	cmd.Flags().Int64Var(&f.targetVersion, "target-version", 0, "Target version to apply changesets up to. If this is empty or 0, all remaining versions in the changeset-dir will be applied.")
+	cmd.Flags().StringVar(&f.logHandlerType, "log-type", "text", "Log handler type. One of 'text' or 'json'.")
+	cmd.Flags().StringVar(&f.logFile, "log-file", "", "If set, log output will be written to this file instead of stdout.")
+}
+
+// loadedRun is a tree loaded from runFlags together with the parameters to run it with.
+type loadedRun struct {
+	tree          Tree
+	changesetInfo changesetInfo
+	params        runParams
+	logOut        *os.File
+}
 
-		loaderParams := LoaderParams{
-			TreeDir:     treeDir,
-			TreeOptions: opts,
-			StoreNames:  changesetInfo.StoreNames,
-			Logger:      treeLogger.With("module", treeType),
+func (l loadedRun) closeLog() {
+	if l.logOut == nil {
+		return
+	}
+	err := l.logOut.Close()
+	if err != nil {
+		slog.Error("error closing log file", "error", err)
+	}
+}
+
+func (f *runFlags) load(treeType string, cfg RunConfig) (loadedRun, error) {
+	loaded, err := f.setup(treeType, cfg)
+	if err != nil {
+		return loadedRun{}, err
+	}
+
+	loaded.params.Logger.Info("Starting benchmark run, loading tree")
+	tree, err := cfg.TreeLoader(loaded.params.LoaderParams)
+	if err != nil {
+		loaded.closeLog()
+		return loadedRun{}, fmt.Errorf("error loading tree: %w", err)
+	}
+	loaded.tree = tree
+	return loaded, nil
+}
+
+// setup validates the flags and opens the log, returning a loadedRun without a tree.
+func (f *runFlags) setup(treeType string, cfg RunConfig) (loadedRun, error) {
+	if f.treeDir == "" {
+		return loadedRun{}, fmt.Errorf("tree-dir is required")
+	}
+
+	if f.changesetDir == "" {
+		return loadedRun{}, fmt.Errorf("changeset-dir is required")
+	}
+
+	changesetInfo, err := readChangesetInfo(f.changesetDir)
+	if err != nil {
+		return loadedRun{}, fmt.Errorf("error reading changeset info file: %w", err)
+	}
+
+	targetVersion := f.targetVersion
+	if targetVersion <= 0 {
+		targetVersion = changesetInfo.Versions
+	}
+
+	// decode db options from json
+	var opts interface{}
+	if cfg.OptionsType != nil {
+		opts = reflect.New(reflect.TypeOf(cfg.OptionsType).Elem()).Interface()
+		if f.treeOptions != "" {
+			if cfg.OptionsType == nil {
+				return loadedRun{}, fmt.Errorf("db-options provided but no OptionsType set in RunConfig")
+			}
+			decoder := json.NewDecoder(bytes.NewReader([]byte(f.treeOptions)))
+			// we disallow unknown fields to catch typos with database options
+			decoder.DisallowUnknownFields()
+			err := decoder.Decode(opts)
+			if err != nil {
+				return loadedRun{}, fmt.Errorf("error unmarshaling db-options: %w", err)
+			}
 		}
+	}
 
-		tree, err := cfg.TreeLoader(loaderParams)
+	var loaded loadedRun
+	logOut := os.Stdout
+	if f.logFile != "" {
+		if f.resume {
+			logOut, err = openAppendLog(f.logFile)
+		} else {
+			logOut, err = os.Create(f.logFile)
+		}
 		if err != nil {
-			return fmt.Errorf("error loading tree: %w", err)
+			return loadedRun{}, fmt.Errorf("error creating log file: %w", err)
 		}
+		loaded.logOut = logOut
+	}
 
-		return run(tree, changesetDir, changesetInfo, runParams{
-			TreeType:      treeType,
-			TargetVersion: targetVersion,
-			Logger:        logger,
-			LoaderParams:  loaderParams,
-		})
+	var handler slog.Handler
+	// Create a separate handler for tree logger at info level
+	var treeHandler slog.Handler
+	switch f.logHandlerType {
+	case "text":
+		handler = slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})
+		treeHandler = slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: slog.LevelInfo})
+	case "json":
+		handler = slog.NewJSONHandler(logOut, &slog.HandlerOptions{Level: slog.LevelDebug})
+		treeHandler = slog.NewJSONHandler(logOut, &slog.HandlerOptions{Level: slog.LevelInfo, AddSource: true})
+	default:
+		loaded.closeLog()
+		return loadedRun{}, fmt.Errorf("unknown log handler type: %s", f.logHandlerType)
 	}
 
-	rootCmd := &cobra.Command{}
-	rootCmd.AddCommand(cmd)
-	return Runner{Command: rootCmd}
+	logger := slog.New(handler).With("module", "runner")
+	treeLogger := slog.New(treeHandler)
+
+	loaderParams := LoaderParams{
+		TreeDir:     f.treeDir,
+		TreeOptions: opts,
+		StoreNames:  changesetInfo.StoreNames,
+		Logger:      treeLogger.With("module", treeType),
+	}
+
+	loaded.changesetInfo = changesetInfo
+	loaded.params = runParams{
+		TreeType:      treeType,
+		TargetVersion: targetVersion,
+		Logger:        logger,
+		LoaderParams:  loaderParams,
+		Resume:        f.resume,
+	}
+	return loaded, nil
+}
+
+// openAppendLog opens a log file for appending, creating it if needed. If a previous run was killed while
+// writing a line, the partial line is terminated so that the next entry starts on a line of its own.
+func openAppendLog(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return file, nil
+	}
+	last := make([]byte, 1)
+	_, err = file.ReadAt(last, info.Size()-1)
+	if err == nil && last[0] != '\n' {
+		_, err = file.Write([]byte{'\n'})
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
 }
 
 type runParams struct {
@@ -189,6 +369,26 @@ type runParams struct {
 	Logger        *slog.Logger
 	LoaderParams  LoaderParams
 	TreeType      string
+	// Metrics, if set, are updated as versions are committed.
+	Metrics *runMetrics
+	// Profiler, if set, writes profiles for ranges of versions.
+	Profiler *profiler
+	// Resume indicates that the run continues a previous run in the same db dir and log file.
+	Resume bool
+	// Verifier, if set, records and checks the hashes of each committed version.
+	Verifier *hashVerifier
+	// Reads configures the point reads interleaved with the writes.
+	Reads ReadParams
+	// Queries configures the concurrent queries against the last committed version.
+	Queries QueryParams
+	// Scans configures the prefix scans performed after each commit.
+	Scans ScanParams
+	// History configures the historical queries performed after all changesets have been applied.
+	History HistoryParams
+	// Proofs configures the proofs generated after each commit.
+	Proofs ProofParams
+	// CommitStats configures the rolling summaries of commit durations.
+	CommitStats CommitStatsParams
 }
 
 func run(tree Tree, changesetDir string, changesetInfo changesetInfo, params runParams) error {
@@ -205,7 +405,15 @@ func run(tree Tree, changesetDir string, changesetInfo changesetInfo, params run
 	}()
 
 	version := tree.Version()
+	startVersion := version
 	target := params.TargetVersion
+	if params.Resume {
+		// versions logged by the previous run after resumed_version were not persisted and are applied again
+		logger.Info("resumed run",
+			"resumed_version", version,
+			"target_version", target,
+		)
+	}
 	logger.Info("starting run",
 		"start_version", version,
 		"target_version", target,
@@ -218,23 +426,106 @@ func run(tree Tree, changesetDir string, changesetInfo changesetInfo, params run
 
 	captureSystemInfo(logger)
 
+	err := params.CommitStats.validate()
+	if err != nil {
+		return err
+	}
+	latencies := newCommitLatencies(params.CommitStats)
+
+	var loads workloads
+	if params.Reads.Ratio > 0 {
+		var err error
+		loads.reads, err = newReadWorkload(tree, params.Reads)
+		if err != nil {
+			return err
+		}
+	}
+	if params.Scans.Count > 0 {
+		var err error
+		loads.scans, err = newScanWorkload(tree, params.Scans)
+		if err != nil {
+			return err
+		}
+	}
+	if params.Proofs.Count > 0 {
+		var err error
+		loads.proofs, err = newProofWorkload(tree, params.Proofs)
+		if err != nil {
+			return err
+		}
+	}
+	if params.History.Queries > 0 {
+		var err error
+		loads.history, err = newHistoryWorkload(tree, params.History)
+		if err != nil {
+			return err
+		}
+	}
+	if params.Queries.Workers > 0 {
+		var err error
+		loads.queries, err = newQueryLoad(tree, params.Queries)
+		if err != nil {
+			return err
+		}
+		defer loads.close()
+	}
+
 	closeCh := make(chan struct{})
 	currentVersion := atomic.Int64{}
 	currentVersion.Store(version)
-	doneCh := measureBackgroundStats(logger, &currentVersion, params.LoaderParams.TreeDir, closeCh)
+	params.Metrics.setVersion(version)
+	doneCh := measureBackgroundStats(logger, &currentVersion, params.LoaderParams.TreeDir, params.Metrics, closeCh)
+
+	defer func() {
+		// profiles of the last range are still written if the run fails
+		err := params.Profiler.stop(version)
+		if err != nil {
+			logger.Error("error writing profiles", "error", err)
+		}
+	}()
 
 	i := 0
 	for version < target {
 		version++
 		currentVersion.Store(version)
-		err := applyVersion(logger, tree, changesetDir, version)
+		err := params.Profiler.beforeVersion(version)
+		if err != nil {
+			return err
+		}
+		commitInfo, commitDuration, err := applyVersion(logger, tree, changesetDir, version, &loads, params.Metrics)
 		if err != nil {
 			return fmt.Errorf("error applying version %d: %w", version, err)
 		}
+		latencies.record(logger, version, commitDuration)
+		if params.Verifier != nil {
+			err = params.Verifier.check(version, commitInfo)
+			if err != nil {
+				logger.Error("hash verification failed", "version", version, "error", err)
+				return err
+			}
+		}
+		err = params.Profiler.afterVersion(version)
+		if err != nil {
+			return err
+		}
 		i++
 	}
 
-	err := tree.Close()
+	latencies.flush(logger)
+
+	err = params.Profiler.stop(version)
+	if err != nil {
+		return err
+	}
+
+	// queries must stop before the tree is closed
+	loads.close()
+
+	if loads.history != nil {
+		loads.history.run(logger, startVersion+1, version)
+	}
+
+	err = tree.Close()
 	if err != nil {
 		return fmt.Errorf("error closing tree: %w", err)
 	}
@@ -243,6 +534,7 @@ func run(tree Tree, changesetDir string, changesetInfo changesetInfo, params run
 	logger.Info(
 		"benchmark run complete",
 		"versions_applied", i,
+		"commit_latency", latencies.summary(),
 	)
 
 	close(closeCh)
@@ -305,11 +597,12 @@ func captureSystemInfo(logger *slog.Logger) {
 	_, _ = cpu.Percent(0, true)
 }
 
-func applyVersion(logger *slog.Logger, tree Tree, changesetDir string, version int64) error {
+// applyVersion applies and commits the changeset of version, returning the new commit info and the duration of the commit.
+func applyVersion(logger *slog.Logger, tree Tree, changesetDir string, version int64, loads *workloads, metrics *runMetrics) (CommitInfo, time.Duration, error) {
 	dataFilename := changesetDataFilename(changesetDir, version)
 	dataFile, err := os.Open(dataFilename)
 	if err != nil {
-		return fmt.Errorf("error opening changeset file for version %d: %w", version, err)
+		return CommitInfo{}, 0, fmt.Errorf("error opening changeset file for version %d: %w", version, err)
 	}
 	defer func() {
 		err := dataFile.Close()
@@ -321,68 +614,173 @@ func applyVersion(logger *slog.Logger, tree Tree, changesetDir string, version i
 
 	logger.Info("applying changeset", "version", version, "file", dataFilename)
 	i := 0
+	// duration, decodeDuration and applyDuration exclude the interleaved workloads, which take workloadDuration,
+	// so that ops_per_sec is the write throughput with and without read workloads
+	var decodeDuration, applyDuration, workloadDuration time.Duration
 	startTime := time.Now()
 	for {
 		if i%10_000 == 0 && i > 0 {
 			logger.Debug("applied changes", "version", version, "count", i)
 		}
+		decodeStart := time.Now()
 		var storeKVPair storev1beta1.StoreKVPair
-		err := protodelim.UnmarshalFrom(reader, &storeKVPair)
+		err := changesetUnmarshalOptions.UnmarshalFrom(reader, &storeKVPair)
 		if err != nil {
 			if err == io.EOF {
+				decodeDuration += time.Since(decodeStart)
 				break
 			}
-			return fmt.Errorf("error at entry %d reading changeset: %w", i, err)
+			return CommitInfo{}, 0, fmt.Errorf("error at entry %d reading changeset: %w", i, err)
 		}
+		applyStart := time.Now()
+		decodeDuration += applyStart.Sub(decodeStart)
 
 		err = tree.ApplyUpdate(storeKVPair.StoreKey, storeKVPair.Key, storeKVPair.Value, storeKVPair.Delete)
 		if err != nil {
-			return fmt.Errorf("error at entry %d applying update: %w", i, err)
+			return CommitInfo{}, 0, fmt.Errorf("error at entry %d applying update: %w", i, err)
+		}
+		workloadStart := time.Now()
+		applyDuration += workloadStart.Sub(applyStart)
+
+		err = loads.afterUpdate(&storeKVPair)
+		workloadDuration += time.Since(workloadStart)
+		if err != nil {
+			return CommitInfo{}, 0, fmt.Errorf("error at entry %d: %w", i, err)
 		}
 
 		i++
 	}
 	logger.Info("applied all changes, commiting", "version", version, "count", i)
 
-	err = tree.Commit()
+	loads.beforeCommit()
+
+	commitStart := time.Now()
+	commitInfo, err := tree.Commit()
+	commitDuration := time.Since(commitStart)
 	if err != nil {
-		return fmt.Errorf("error committing version %d: %w", version, err)
+		return CommitInfo{}, 0, fmt.Errorf("error committing version %d: %w", version, err)
 	}
 
 	if tree.Version() != version {
-		return fmt.Errorf("committed version %d does not match expected version %d", tree.Version(), version)
+		return CommitInfo{}, 0, fmt.Errorf("committed version %d does not match expected version %d", tree.Version(), version)
 	}
 
-	duration := time.Since(startTime)
+	duration := time.Since(startTime) - workloadDuration
 	opsPerSec := float64(i) / duration.Seconds()
+	metrics.observeCommit(version, i, opsPerSec, commitDuration)
 
-	// get mem stats
-
-	logger.Info(
-		"committed version",
+	attrs := []any{
 		"version", version,
 		"duration", duration,
+		"decode_duration", decodeDuration,
+		"apply_duration", applyDuration,
+		"commit_duration", commitDuration,
+		"workload_duration", workloadDuration,
 		"count", i,
 		"ops_per_sec", opsPerSec,
-	)
+		"hash", hex.EncodeToString(commitInfo.Hash),
+		"store_hashes", commitInfo.storeHashesHex(),
+	}
+	if commitInfo.StoreCommitDurations != nil {
+		attrs = append(attrs, "store_commit_durations", commitInfo.StoreCommitDurations)
+	}
+	logger.Info("committed version", attrs...)
+
+	err = loads.afterCommit(logger, version, commitInfo)
+	if err != nil {
+		return CommitInfo{}, 0, err
+	}
+
+	return commitInfo, commitDuration, nil
+}
+
+// workloads are the optional workloads run alongside the changesets, nil fields are disabled.
+type workloads struct {
+	reads   *readWorkload
+	scans   *scanWorkload
+	history *historyWorkload
+	proofs  *proofWorkload
+	queries *queryLoad
+}
 
+func (w *workloads) afterUpdate(pair *storev1beta1.StoreKVPair) error {
+	if w.queries != nil {
+		w.queries.afterUpdate(pair.StoreKey, pair.Key, pair.Value, pair.Delete)
+	}
+	if w.scans != nil {
+		w.scans.afterUpdate(pair.StoreKey, pair.Key, pair.Value, pair.Delete)
+	}
+	if w.history != nil {
+		w.history.afterUpdate(pair.StoreKey, pair.Key, pair.Value, pair.Delete)
+	}
+	if w.proofs != nil {
+		w.proofs.afterUpdate(pair.StoreKey, pair.Key, pair.Value, pair.Delete)
+	}
+	if w.reads != nil {
+		return w.reads.afterUpdate(pair.StoreKey, pair.Key, pair.Value, pair.Delete)
+	}
 	return nil
 }
 
-func measureBackgroundStats(logger *slog.Logger, currentVersion *atomic.Int64, path string, closeCh <-chan struct{}) <-chan struct{} {
+func (w *workloads) beforeCommit() {
+	if w.queries != nil {
+		w.queries.beforeCommit()
+	}
+}
+
+func (w *workloads) afterCommit(logger *slog.Logger, version int64, commitInfo CommitInfo) error {
+	if w.reads != nil {
+		w.reads.logVersion(logger, version)
+	}
+	if w.queries != nil {
+		w.queries.afterCommit(logger, version)
+	}
+	if w.proofs != nil {
+		w.proofs.afterCommit(logger, version, commitInfo)
+	}
+	if w.scans != nil {
+		return w.scans.afterCommit(logger, version)
+	}
+	return nil
+}
+
+// close stops the query workers, it is safe to call more than once.
+func (w *workloads) close() {
+	if w.queries != nil {
+		w.queries.close()
+		w.queries = nil
+	}
+}
+
+func measureBackgroundStats(logger *slog.Logger, currentVersion *atomic.Int64, path string, metrics *runMetrics, closeCh <-chan struct{}) <-chan struct{} {
 	doneChan := make(chan struct{})
 	go func() {
 		fastStatTicker := time.NewTicker(1 * time.Second)
 		diskStatTicker := time.NewTicker(10 * time.Second)
 		defer fastStatTicker.Stop()
 		defer diskStatTicker.Stop()
+		proc, err := process.NewProcess(int32(os.Getpid()))
+		if err != nil {
+			logger.Warn("could not open own process for rss", "error", err)
+		}
 		for {
 			select {
 			case <-fastStatTicker.C:
 				// capture mem stats
 				var memStats runtime.MemStats
 				runtime.ReadMemStats(&memStats)
+				// rss includes memory allocated outside of the go runtime, e.g. by cgo dbs
+				var rss uint64
+				if proc != nil {
+					procMem, err := proc.MemoryInfo()
+					if err != nil {
+						logger.Warn("could not read process memory info", "error", err)
+					} else {
+						rss = procMem.RSS
+					}
+				}
 				logger.Info("mem stats", "version", currentVersion.Load(),
+					"rss", humanize.Bytes(rss),
 					"alloc", humanize.Bytes(memStats.Alloc),
 					"total_alloc", humanize.Bytes(memStats.TotalAlloc),
 					"sys", humanize.Bytes(memStats.Sys),
@@ -420,6 +818,7 @@ func measureBackgroundStats(logger *slog.Logger, currentVersion *atomic.Int64, p
 				// capture disk usage (expensive operation)
 				size := getDirSize(logger, path)
 				logger.Info("disk usage", "version", currentVersion.Load(), "size", humanize.Bytes(size))
+				metrics.setDBDirSize(size)
 
 			case <-closeCh:
 				close(doneChan)
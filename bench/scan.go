@@ -0,0 +1,122 @@
+package bench
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// ScanParams configure the prefix scans performed after each commit.
+type ScanParams struct {
+	// Count is the number of prefix scans per version. Zero disables scans.
+	Count int
+	// PrefixLen is the number of leading bytes of a known key used as the scanned prefix.
+	PrefixLen int
+	// Width is the maximum number of entries read by each scan.
+	Width int
+}
+
+// prefixEnd returns the exclusive end of the key range covering all keys starting with prefix,
+// or nil if there is no upper bound.
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// drainIterator reads up to limit entries from itr and closes it, returning the number of entries read.
+func drainIterator(itr Iterator, limit int) (items int, err error) {
+	defer func() {
+		closeErr := itr.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	for ; itr.Valid() && items < limit; itr.Next() {
+		_ = itr.Value()
+		items++
+	}
+	return items, itr.Error()
+}
+
+// scanWorkload performs prefix scans of the latest state between commits,
+// similar to modules iterating over e.g. the balances of an address.
+type scanWorkload struct {
+	iterable Iterable
+	params   ScanParams
+	rng      *rand.Rand
+	keys     *keySamples
+}
+
+func newScanWorkload(tree Tree, params ScanParams) (*scanWorkload, error) {
+	iterable, ok := tree.(Iterable)
+	if !ok {
+		return nil, fmt.Errorf("tree of type %T does not implement Iterable", tree)
+	}
+	rng := rand.New(rand.NewPCG(2, 0))
+	return &scanWorkload{
+		iterable: iterable,
+		params:   params,
+		rng:      rng,
+		keys:     newKeySamples(rng),
+	}, nil
+}
+
+func (w *scanWorkload) afterUpdate(storeKey string, key, value []byte, delete bool) {
+	w.keys.update(storeKey, key, value, delete)
+}
+
+// afterCommit performs the scans of the version and logs their stats.
+func (w *scanWorkload) afterCommit(logger *slog.Logger, version int64) error {
+	if w.keys.empty() {
+		return nil
+	}
+	var ascLatency, descLatency latencyHistogram
+	items, empty := 0, 0
+	for i := 0; i < w.params.Count; i++ {
+		storeKey, key := w.keys.random(false)
+		prefix := key[:min(w.params.PrefixLen, len(key))]
+		ascending := w.rng.IntN(2) == 0
+
+		start := time.Now()
+		itr, err := w.iterable.Iterator(storeKey, prefix, prefixEnd(prefix), ascending)
+		if err != nil {
+			return fmt.Errorf("error creating iterator for prefix %X in store %s: %w", prefix, storeKey, err)
+		}
+		n, err := drainIterator(itr, w.params.Width)
+		if err != nil {
+			return fmt.Errorf("error scanning prefix %X in store %s: %w", prefix, storeKey, err)
+		}
+		if ascending {
+			ascLatency.record(time.Since(start))
+		} else {
+			descLatency.record(time.Since(start))
+		}
+
+		items += n
+		if n == 0 {
+			empty++
+		}
+	}
+
+	var latency latencyHistogram
+	latency.merge(&ascLatency)
+	latency.merge(&descLatency)
+	logger.Info("scan stats",
+		"version", version,
+		"scans", w.params.Count,
+		"items", items,
+		"empty_scans", empty,
+		"latency", latency.summary(true),
+		"ascending_latency", ascLatency.summary(false),
+		"descending_latency", descLatency.summary(false),
+	)
+	return nil
+}
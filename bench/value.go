@@ -0,0 +1,197 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Value generation modes of StoreParams.ValueMode.
+const (
+	// ValueRandom is random bytes, which are incompressible.
+	ValueRandom = "random"
+	// ValueCompressible is random bytes of which a ValueCompressibility fraction repeats earlier parts of the value.
+	ValueCompressible = "compressible"
+	// ValueProtobuf is a protobuf encoded message of varint amounts and sequences, bech32 addresses, coins
+	// and hashes, which compresses to about 0.86 of its size with snappy and 0.75 with deflate.
+	ValueProtobuf = "protobuf"
+	// ValueCounter is an 8 byte big-endian counter which increases with every write to the store, like
+	// sequence numbers. ValueMean and ValueStdDev are ignored.
+	ValueCounter = "counter"
+)
+
+// compressibleSegment is the maximum size of the segments of a ValueCompressible value, which start with random
+// bytes followed by a copy of earlier bytes of the value. A copy must be long for compressors to gain from it,
+// as snappy spends up to 3 bytes on every match.
+const compressibleSegment = 128
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var protobufDenoms = []string{
+	"uatom",
+	"uosmo",
+	"stake",
+	"ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2",
+	"gamm/pool/1",
+}
+
+// valueGen generates the values of a store according to its params.
+type valueGen struct {
+	params  StoreParams
+	counter uint64
+	// last are the last values written to the existing keys, only kept with ValueReuse
+	last map[string][]byte
+}
+
+func newValueGen(c StoreParams) (*valueGen, error) {
+	switch c.ValueMode {
+	case "", ValueRandom, ValueProtobuf:
+	case ValueCompressible:
+		if c.ValueCompressibility < 0 || c.ValueCompressibility >= 1 {
+			return nil, fmt.Errorf("value compressibility must be in [0, 1), got %g", c.ValueCompressibility)
+		}
+	case ValueCounter:
+		if c.ValueReuse != 0 {
+			return nil, fmt.Errorf("value reuse can't be used with %s values", ValueCounter)
+		}
+	default:
+		return nil, fmt.Errorf("unknown value mode %q, expected one of %s, %s, %s or %s",
+			c.ValueMode, ValueRandom, ValueCompressible, ValueProtobuf, ValueCounter)
+	}
+	if c.ValueReuse < 0 || c.ValueReuse > 1 {
+		return nil, fmt.Errorf("value reuse must be in [0, 1], got %g", c.ValueReuse)
+	}
+	if c.ValueMutations < 0 {
+		return nil, fmt.Errorf("value mutations must not be negative, got %d", c.ValueMutations)
+	}
+	g := &valueGen{params: c}
+	if c.ValueReuse > 0 {
+		g.last = map[string][]byte{}
+	}
+	return g, nil
+}
+
+// create generates the value of a created key.
+func (g *valueGen) create(rng *rand.Rand, key []byte) []byte {
+	return g.keep(key, g.gen(rng))
+}
+
+// update generates the value of an updated key.
+func (g *valueGen) update(rng *rand.Rand, key []byte) []byte {
+	if g.last == nil || rng.Float64() >= g.params.ValueReuse {
+		return g.keep(key, g.gen(rng))
+	}
+	last, ok := g.last[string(key)]
+	if !ok {
+		return g.keep(key, g.gen(rng))
+	}
+	value := bytes.Clone(last)
+	for range g.params.ValueMutations {
+		value[rng.IntN(len(value))] = byte(rng.IntN(256))
+	}
+	return g.keep(key, value)
+}
+
+// delete forgets the last value of a deleted key.
+func (g *valueGen) delete(key []byte) {
+	if g.last != nil {
+		delete(g.last, string(key))
+	}
+}
+
+// keep remembers value as the last value of key if values are reused and returns it.
+func (g *valueGen) keep(key, value []byte) []byte {
+	if g.last != nil {
+		g.last[string(key)] = value
+	}
+	return value
+}
+
+func (g *valueGen) gen(rng *rand.Rand) []byte {
+	switch g.params.ValueMode {
+	case ValueCompressible:
+		return genCompressible(rng, genLength(rng, g.params.ValueMean, g.params.ValueStdDev), g.params.ValueCompressibility)
+	case ValueProtobuf:
+		return genProtobuf(rng, genLength(rng, g.params.ValueMean, g.params.ValueStdDev))
+	case ValueCounter:
+		g.counter++
+		return bigEndian(g.counter, 8)
+	default:
+		return genBytes(rng, g.params.ValueMean, g.params.ValueStdDev)
+	}
+}
+
+// genCompressible returns length bytes made of segments of up to compressibleSegment bytes, of which the first
+// 1 - compressibility are random and the rest a copy of the bytes at a random position within the previous
+// segment length, so that the copy is found in the same compression block. A copy may overlap itself, so that
+// even the first segment compresses.
+func genCompressible(rng *rand.Rand, length int, compressibility float64) []byte {
+	b := make([]byte, length)
+	segment := min(compressibleSegment, length)
+	random := max(1, int(math.Round(float64(segment)*(1-compressibility))))
+	for start := 0; start < length; start += segment {
+		end := min(start+segment, length)
+		pos := min(start+random, end)
+		for i := start; i < pos; i++ {
+			b[i] = byte(rng.IntN(256))
+		}
+		if pos == end {
+			continue
+		}
+		src := max(0, pos-segment) + rng.IntN(min(pos, segment))
+		for i := pos; i < end; i++ {
+			b[i] = b[src+i-pos]
+		}
+	}
+	return b
+}
+
+// genProtobuf returns a protobuf encoded message of at least length bytes.
+func genProtobuf(rng *rand.Rand, length int) []byte {
+	var b []byte
+	for field := protowire.Number(1); len(b) < length; field++ {
+		switch rng.IntN(5) {
+		case 0:
+			// a sequence or an enum
+			b = protowire.AppendTag(b, field, protowire.VarintType)
+			b = protowire.AppendVarint(b, rng.Uint64N(1<<16))
+		case 1:
+			b = protowire.AppendTag(b, field, protowire.VarintType)
+			b = protowire.AppendVarint(b, rng.Uint64N(1_000_000_000_000))
+		case 2:
+			b = protowire.AppendTag(b, field, protowire.BytesType)
+			b = protowire.AppendString(b, genBech32Address(rng))
+		case 3:
+			// a Coin, whose amount is a decimal string
+			var coin []byte
+			coin = protowire.AppendTag(coin, 1, protowire.BytesType)
+			coin = protowire.AppendString(coin, protobufDenoms[rng.IntN(len(protobufDenoms))])
+			coin = protowire.AppendTag(coin, 2, protowire.BytesType)
+			coin = protowire.AppendString(coin, strconv.FormatUint(rng.Uint64N(1_000_000_000_000), 10))
+			b = protowire.AppendTag(b, field, protowire.BytesType)
+			b = protowire.AppendBytes(b, coin)
+		case 4:
+			// a hash or a public key
+			hash := make([]byte, 32)
+			for i := range hash {
+				hash[i] = byte(rng.IntN(256))
+			}
+			b = protowire.AppendTag(b, field, protowire.BytesType)
+			b = protowire.AppendBytes(b, hash)
+		}
+	}
+	return b
+}
+
+func genBech32Address(rng *rand.Rand) string {
+	addr := make([]byte, 0, 45)
+	addr = append(addr, "cosmos1"...)
+	for range 38 {
+		addr = append(addr, bech32Charset[rng.IntN(len(bech32Charset))])
+	}
+	return string(addr)
+}
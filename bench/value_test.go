@@ -0,0 +1,158 @@
+package bench
+
+import (
+	"bytes"
+	"compress/flate"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestValueGenValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  StoreParams
+		wantErr bool
+	}{
+		{name: "default", params: StoreParams{}},
+		{name: "compressible", params: StoreParams{ValueMode: ValueCompressible, ValueCompressibility: 0.5}},
+		{name: "compressibility of 1", params: StoreParams{ValueMode: ValueCompressible, ValueCompressibility: 1}, wantErr: true},
+		{name: "negative compressibility", params: StoreParams{ValueMode: ValueCompressible, ValueCompressibility: -0.1}, wantErr: true},
+		{name: "counter with reuse", params: StoreParams{ValueMode: ValueCounter, ValueReuse: 0.5}, wantErr: true},
+		{name: "reuse of 1", params: StoreParams{ValueMode: ValueProtobuf, ValueReuse: 1, ValueMutations: 2}},
+		{name: "reuse above 1", params: StoreParams{ValueReuse: 1.5}, wantErr: true},
+		{name: "negative mutations", params: StoreParams{ValueReuse: 0.5, ValueMutations: -1}, wantErr: true},
+		{name: "unknown mode", params: StoreParams{ValueMode: "json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newValueGen(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newValueGen() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// deflateRatio returns the compressed size of b relative to its size.
+func deflateRatio(t *testing.T, b []byte) float64 {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return float64(buf.Len()) / float64(len(b))
+}
+
+func TestGenCompressibleRatio(t *testing.T) {
+	for _, length := range []int{64, 256, 1000} {
+		for _, compressibility := range []float64{0, 0.25, 0.5, 0.75, 0.9} {
+			rng := rand.New(rand.NewPCG(1, 2))
+			var values []byte
+			for range 500 {
+				value := genCompressible(rng, length, compressibility)
+				if len(value) != length {
+					t.Fatalf("value has length %d, want %d", len(value), length)
+				}
+				values = append(values, value...)
+			}
+			// the random part doesn't compress and the copies cost a few bytes each
+			ratio := deflateRatio(t, values)
+			if want := 1 - compressibility; ratio < want-0.01 || ratio > want+0.06 {
+				t.Errorf("values of %d bytes with compressibility %g compress to %.3f, want %.2f to %.2f",
+					length, compressibility, ratio, want-0.01, want+0.06)
+			}
+		}
+	}
+}
+
+func TestGenCompressibleShort(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for length := range 10 {
+		if value := genCompressible(rng, length, 0.9); len(value) != length {
+			t.Errorf("value has length %d, want %d", len(value), length)
+		}
+	}
+}
+
+func TestGenProtobuf(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var values []byte
+	for range 500 {
+		value := genProtobuf(rng, 200)
+		if len(value) < 200 {
+			t.Fatalf("value has length %d, want at least 200", len(value))
+		}
+		// the value is a valid message
+		for b := value; len(b) > 0; {
+			_, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("invalid tag in %X: %v", value, protowire.ParseError(n))
+			}
+			b = b[n:]
+			n = protowire.ConsumeFieldValue(0, typ, b)
+			if n < 0 {
+				t.Fatalf("invalid field in %X: %v", value, protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+		values = append(values, value...)
+	}
+	if ratio := deflateRatio(t, values); math.Abs(ratio-0.75) > 0.08 {
+		t.Errorf("protobuf values compress to %.3f, want about 0.75", ratio)
+	}
+}
+
+func TestValueGenCounter(t *testing.T) {
+	g, err := newValueGen(StoreParams{ValueMode: ValueCounter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := uint64(1); i <= 3; i++ {
+		if got := g.update(rng, []byte("a")); !bytes.Equal(got, bigEndian(i, 8)) {
+			t.Errorf("value %d is %X", i, got)
+		}
+	}
+}
+
+func TestValueGenReuse(t *testing.T) {
+	g, err := newValueGen(StoreParams{ValueMean: 100, ValueReuse: 1, ValueMutations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	key := []byte("a")
+	last := g.create(rng, key)
+	for range 10 {
+		value := g.update(rng, key)
+		if len(value) != len(last) {
+			t.Fatalf("reused value has length %d, want %d", len(value), len(last))
+		}
+		changed := 0
+		for i := range value {
+			if value[i] != last[i] {
+				changed++
+			}
+		}
+		if changed > 2 {
+			t.Errorf("reused value differs in %d bytes, want at most 2", changed)
+		}
+		last = value
+	}
+
+	// the last value of a deleted key is forgotten, so a recreated key gets a new value
+	g.delete(key)
+	if _, ok := g.last[string(key)]; ok {
+		t.Error("kept the last value of a deleted key")
+	}
+}
@@ -0,0 +1,154 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// VersionHashes is the record written for each committed version by the verify command.
+type VersionHashes struct {
+	Version     int64             `json:"version"`
+	Hash        string            `json:"hash"`
+	StoreHashes map[string]string `json:"store_hashes"`
+}
+
+func newVersionHashes(version int64, info CommitInfo) VersionHashes {
+	return VersionHashes{
+		Version:     version,
+		Hash:        hex.EncodeToString(info.Hash),
+		StoreHashes: info.storeHashesHex(),
+	}
+}
+
+// Divergence describes the first point at which two hash logs differ.
+type Divergence struct {
+	Version int64 `json:"version"`
+	// Store is the name of the diverged store, empty if only the aggregate hash or the set of stores differs.
+	Store    string `json:"store,omitempty"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (d Divergence) Error() string {
+	if d.Store == "" {
+		return fmt.Sprintf("hash divergence at version %d: expected %s, got %s", d.Version, d.Expected, d.Actual)
+	}
+	return fmt.Sprintf("hash divergence at version %d in store %s: expected %s, got %s", d.Version, d.Store, d.Expected, d.Actual)
+}
+
+// CompareVersionHashes compares the hashes of a single version, returning nil if they match.
+func CompareVersionHashes(expected, actual VersionHashes) *Divergence {
+	names := make([]string, 0, len(expected.StoreHashes))
+	for name := range expected.StoreHashes {
+		names = append(names, name)
+	}
+	for name := range actual.StoreHashes {
+		if _, ok := expected.StoreHashes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		exp, act := expected.StoreHashes[name], actual.StoreHashes[name]
+		if exp != act {
+			return &Divergence{Version: expected.Version, Store: name, Expected: exp, Actual: act}
+		}
+	}
+	if expected.Hash != actual.Hash {
+		return &Divergence{Version: expected.Version, Expected: expected.Hash, Actual: actual.Hash}
+	}
+	return nil
+}
+
+// CompareHashLogs compares two hash logs version by version and returns the first divergence.
+// Only versions present in both logs are compared. It also returns the number of versions compared.
+func CompareHashLogs(expected, actual []VersionHashes) (int, *Divergence) {
+	actualByVersion := make(map[int64]VersionHashes, len(actual))
+	for _, h := range actual {
+		actualByVersion[h.Version] = h
+	}
+	compared := 0
+	for _, exp := range expected {
+		act, ok := actualByVersion[exp.Version]
+		if !ok {
+			continue
+		}
+		compared++
+		if d := CompareVersionHashes(exp, act); d != nil {
+			return compared, d
+		}
+	}
+	return compared, nil
+}
+
+// ReadHashLog reads a jsonl hash log written by the verify command, ordered by version.
+func ReadHashLog(filename string) ([]VersionHashes, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening hash log: %w", err)
+	}
+	defer f.Close()
+
+	var res []VersionHashes
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var h VersionHashes
+		err := decoder.Decode(&h)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding hash log %s: %w", filename, err)
+		}
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	return res, nil
+}
+
+// hashVerifier writes the hashes of each committed version and optionally checks them against a reference log.
+type hashVerifier struct {
+	out       *json.Encoder
+	reference map[int64]VersionHashes
+}
+
+func newHashVerifier(out io.Writer, referenceFile string) (*hashVerifier, error) {
+	v := &hashVerifier{out: json.NewEncoder(out)}
+	if referenceFile != "" {
+		reference, err := ReadHashLog(referenceFile)
+		if err != nil {
+			return nil, err
+		}
+		v.reference = make(map[int64]VersionHashes, len(reference))
+		for _, h := range reference {
+			v.reference[h.Version] = h
+		}
+	}
+	return v, nil
+}
+
+// check records the hashes for version and returns a *Divergence error if they differ from the reference.
+// The hashes are always written first so the hash log also contains the diverging version.
+func (v *hashVerifier) check(version int64, info CommitInfo) error {
+	actual := newVersionHashes(version, info)
+	err := v.out.Encode(actual)
+	if err != nil {
+		return fmt.Errorf("error writing hash log: %w", err)
+	}
+	if v.reference == nil {
+		return nil
+	}
+	expected, ok := v.reference[version]
+	if !ok {
+		return fmt.Errorf("reference hash log has no entry for version %d", version)
+	}
+	if d := CompareVersionHashes(expected, actual); d != nil {
+		return d
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hashLog returns a log of versions 1 to n whose hashes are derived from the version and store names.
+func hashLog(n int64, stores ...string) []VersionHashes {
+	res := make([]VersionHashes, 0, n)
+	for version := int64(1); version <= n; version++ {
+		h := VersionHashes{Version: version, Hash: "agg", StoreHashes: map[string]string{}}
+		for _, store := range stores {
+			h.StoreHashes[store] = store + string(rune('0'+version))
+		}
+		res = append(res, h)
+	}
+	return res
+}
+
+func TestCompareVersionHashes(t *testing.T) {
+	expected := VersionHashes{Version: 7, Hash: "agg", StoreHashes: map[string]string{"acc": "a1", "bank": "b1"}}
+	tests := []struct {
+		name   string
+		actual VersionHashes
+		want   *Divergence
+	}{
+		{
+			name:   "equal",
+			actual: VersionHashes{Version: 7, Hash: "agg", StoreHashes: map[string]string{"acc": "a1", "bank": "b1"}},
+		},
+		{
+			name:   "first store in name order",
+			actual: VersionHashes{Version: 7, Hash: "agg2", StoreHashes: map[string]string{"acc": "a2", "bank": "b2"}},
+			want:   &Divergence{Version: 7, Store: "acc", Expected: "a1", Actual: "a2"},
+		},
+		{
+			name:   "missing store",
+			actual: VersionHashes{Version: 7, Hash: "agg", StoreHashes: map[string]string{"acc": "a1"}},
+			want:   &Divergence{Version: 7, Store: "bank", Expected: "b1"},
+		},
+		{
+			name:   "extra store",
+			actual: VersionHashes{Version: 7, Hash: "agg", StoreHashes: map[string]string{"acc": "a1", "bank": "b1", "gov": "g1"}},
+			want:   &Divergence{Version: 7, Store: "gov", Actual: "g1"},
+		},
+		{
+			name:   "aggregate hash only",
+			actual: VersionHashes{Version: 7, Hash: "agg2", StoreHashes: map[string]string{"acc": "a1", "bank": "b1"}},
+			want:   &Divergence{Version: 7, Expected: "agg", Actual: "agg2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareVersionHashes(expected, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareVersionHashes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareHashLogs(t *testing.T) {
+	// diverged returns a log of n versions whose bank store diverges from hashLog from version on
+	diverged := func(n, version int64) []VersionHashes {
+		res := hashLog(n, "acc", "bank")
+		for i := version - 1; i < n; i++ {
+			res[i].StoreHashes["bank"] = "x"
+		}
+		return res
+	}
+	tests := []struct {
+		name         string
+		expected     []VersionHashes
+		actual       []VersionHashes
+		wantCompared int
+		wantVersion  int64
+	}{
+		{
+			name:         "equal",
+			expected:     hashLog(5, "acc", "bank"),
+			actual:       hashLog(5, "acc", "bank"),
+			wantCompared: 5,
+		},
+		{
+			name:         "shorter actual",
+			expected:     hashLog(8, "acc", "bank"),
+			actual:       hashLog(3, "acc", "bank"),
+			wantCompared: 3,
+		},
+		{
+			name:         "longer actual",
+			expected:     hashLog(3, "acc", "bank"),
+			actual:       hashLog(8, "acc", "bank"),
+			wantCompared: 3,
+		},
+		{
+			name:         "divergence within a shorter actual",
+			expected:     hashLog(8, "acc", "bank"),
+			actual:       diverged(5, 4),
+			wantCompared: 4,
+			wantVersion:  4,
+		},
+		{
+			name:         "divergence within a shorter expected",
+			expected:     diverged(5, 2),
+			actual:       hashLog(8, "acc", "bank"),
+			wantCompared: 2,
+			wantVersion:  2,
+		},
+		{
+			name:         "divergence after the end of the shorter log",
+			expected:     hashLog(3, "acc", "bank"),
+			actual:       diverged(8, 5),
+			wantCompared: 3,
+		},
+		{
+			name:         "versions missing from actual are skipped",
+			expected:     hashLog(6, "acc", "bank"),
+			actual:       append(hashLog(2, "acc", "bank"), diverged(6, 5)[4:]...),
+			wantCompared: 3,
+			wantVersion:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compared, d := CompareHashLogs(tt.expected, tt.actual)
+			if compared != tt.wantCompared {
+				t.Errorf("compared %d versions, want %d", compared, tt.wantCompared)
+			}
+			switch {
+			case tt.wantVersion == 0 && d != nil:
+				t.Errorf("unexpected divergence %v", d)
+			case tt.wantVersion != 0 && d == nil:
+				t.Errorf("no divergence, want one at version %d", tt.wantVersion)
+			case d != nil && (d.Version != tt.wantVersion || d.Store != "bank"):
+				t.Errorf("divergence %v, want one in store bank at version %d", d, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestReadHashLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.hashes.jsonl")
+	log := `{"version":2,"hash":"b","store_hashes":{"s":"2"}}
+{"version":1,"hash":"a","store_hashes":{"s":"1"}}
+`
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadHashLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []VersionHashes{
+		{Version: 1, Hash: "a", StoreHashes: map[string]string{"s": "1"}},
+		{Version: 2, Hash: "b", StoreHashes: map[string]string{"s": "2"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadHashLog() = %+v, want %+v", got, want)
+	}
+
+	// a line cut off by a crash is an error rather than the end of the log
+	if err := os.WriteFile(path, []byte(log+`{"version":3,"ha`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadHashLog(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("ReadHashLog of a cut-off log = %v, want an error naming the file", err)
+	}
+}
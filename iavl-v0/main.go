@@ -2,9 +2,11 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	db "github.com/cosmos/cosmos-db"
 	"github.com/cosmos/iavl"
+	ics23 "github.com/cosmos/ics23/go"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 
 	"github.com/cosmos/iavl-bench/bench"
@@ -15,10 +17,17 @@ type MultiTreeWrapper struct {
 	dbDir   string
 	version int64
 	trees   map[string]*iavl.MutableTree
+	dbs     []*db.GoLevelDB
 }
 
 func (m *MultiTreeWrapper) Close() error {
-	// no official close method for iavl trees
+	// no official close method for iavl trees, but their dbs must be closed before they can be reopened
+	for _, d := range m.dbs {
+		err := d.Close()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -26,10 +35,18 @@ func (m *MultiTreeWrapper) Version() int64 {
 	return m.version
 }
 
-func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+func (m *MultiTreeWrapper) getTree(storeKey string) (*iavl.MutableTree, error) {
 	tree, ok := m.trees[storeKey]
 	if !ok {
-		return fmt.Errorf("store key %s not found", storeKey)
+		return nil, fmt.Errorf("store key %s not found", storeKey)
+	}
+	return tree, nil
+}
+
+func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return err
 	}
 	if delete {
 		_, _, err := tree.Remove(key)
@@ -40,20 +57,97 @@ func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delet
 	}
 }
 
-func (m *MultiTreeWrapper) Commit() error {
-	for _, tree := range m.trees {
-		_, _, err := tree.SaveVersion()
+func (m *MultiTreeWrapper) Commit() (bench.CommitInfo, error) {
+	storeHashes := make(map[string][]byte, len(m.trees))
+	durations := make(map[string]time.Duration, len(m.trees))
+	for storeName, tree := range m.trees {
+		start := time.Now()
+		hash, _, err := tree.SaveVersion()
 		if err != nil {
-			return err
+			return bench.CommitInfo{}, err
 		}
+		storeHashes[storeName] = hash
+		durations[storeName] = time.Since(start)
 	}
 
 	m.version++
 
-	return util.SaveVersion(m.dbDir, m.version)
+	err := util.SaveVersion(m.dbDir, m.version)
+	if err != nil {
+		return bench.CommitInfo{}, err
+	}
+	commitInfo := bench.NewCommitInfo(storeHashes)
+	commitInfo.StoreCommitDurations = durations
+	return commitInfo, nil
+}
+
+func (m *MultiTreeWrapper) Get(storeKey string, key []byte) ([]byte, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Get(key)
+}
+
+func (m *MultiTreeWrapper) Has(storeKey string, key []byte) (bool, error) {
+	value, err := m.Get(storeKey, key)
+	return value != nil, err
+}
+
+// getImmutable returns the tree of storeKey at a committed version.
+// Unlike the MutableTree, it is safe to query concurrently with ApplyUpdate and Commit.
+func (m *MultiTreeWrapper) getImmutable(storeKey string, version int64) (*iavl.ImmutableTree, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	if !tree.VersionExists(version) {
+		return nil, fmt.Errorf("version %d of store %s is not available", version, storeKey)
+	}
+	return tree.GetImmutable(version)
+}
+
+func (m *MultiTreeWrapper) GetAt(storeKey string, version int64, key []byte) ([]byte, error) {
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Get(key)
+}
+
+func (m *MultiTreeWrapper) HasAt(storeKey string, version int64, key []byte) (bool, error) {
+	value, err := m.GetAt(storeKey, version, key)
+	return value != nil, err
+}
+
+func (m *MultiTreeWrapper) Iterator(storeKey string, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Iterator(start, end, ascending)
+}
+
+func (m *MultiTreeWrapper) IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Iterator(start, end, ascending)
+}
+
+func (m *MultiTreeWrapper) GetProof(storeKey string, version int64, key []byte) (*ics23.CommitmentProof, error) {
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	return tree.GetProof(key)
 }
 
 var _ bench.Tree = &MultiTreeWrapper{}
+var _ bench.Reader = &MultiTreeWrapper{}
+var _ bench.Iterable = &MultiTreeWrapper{}
+var _ bench.Prover = &MultiTreeWrapper{}
 
 func main() {
 	bench.Run("iavl/v1", bench.RunConfig{
@@ -64,11 +158,13 @@ func main() {
 				return nil, err
 			}
 			trees := make(map[string]*iavl.MutableTree)
+			var dbs []*db.GoLevelDB
 			for _, storeName := range params.StoreNames {
 				d, err := db.NewGoLevelDBWithOpts(storeName, dbDir, &opt.Options{})
 				if err != nil {
 					return nil, err
 				}
+				dbs = append(dbs, d)
 				tree, err := iavl.NewMutableTree(d, 10_000, true)
 				if err != nil {
 					return nil, fmt.Errorf("error creating store %s: %w", storeName, err)
@@ -83,6 +179,7 @@ func main() {
 			}
 			return &MultiTreeWrapper{
 				trees:   trees,
+				dbs:     dbs,
 				version: version,
 				dbDir:   dbDir,
 			}, nil
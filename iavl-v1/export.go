@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	storev1beta1 "cosmossdk.io/api/cosmos/store/v1beta1"
+	"cosmossdk.io/log"
+	"github.com/cosmos/iavl"
+	"github.com/cosmos/iavl/db"
+	"github.com/spf13/cobra"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/cosmos/iavl-bench/bench"
+)
+
+// appDBName is the name of the leveldb of a Cosmos SDK node, which holds the iavl tree of every store
+// under the prefix "s/k:<store name>/".
+const appDBName = "application"
+
+type exportParams struct {
+	dbDir        string
+	appDB        bool
+	stores       []string
+	startVersion int64
+	endVersion   int64
+	cacheSize    int
+}
+
+func newExportCommand() *cobra.Command {
+	var params exportParams
+	cmd := &cobra.Command{
+		Use:   "export-changesets [out-dir]",
+		Short: "Export the changes between consecutive versions of existing iavl v1 trees as changesets",
+		Long: `Export the changes between consecutive versions of existing iavl v1 trees as changesets.
+
+By default db-dir has the layout which the bench command creates, one leveldb per store. With --app-db,
+db-dir is the data dir of a Cosmos SDK node holding application.db, which must not be in use.
+The first exported version becomes version 1 and holds the complete state of the trees at that version
+if the version before it is not available, e.g. because it is the first version or was pruned.
+The changes of a version are exported in key order, so the tree shapes and root hashes of runs on the
+changesets can differ from the exported trees, whose shapes depend on the order the changes were applied.`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&params.dbDir, "db-dir", "", "dir holding the trees to export")
+	cmd.Flags().BoolVar(&params.appDB, "app-db", false, "if true, db-dir is the data dir of a Cosmos SDK node holding application.db")
+	cmd.Flags().StringSliceVar(&params.stores, "stores", nil, "stores to export, required with --app-db. Defaults to all stores in db-dir")
+	cmd.Flags().Int64Var(&params.startVersion, "start-version", 0, "if non-zero, the first version to export. Defaults to the first available version")
+	cmd.Flags().Int64Var(&params.endVersion, "end-version", 0, "if non-zero, the last version to export. Defaults to the latest version")
+	cmd.Flags().IntVar(&params.cacheSize, "cache-size", 100_000, "node cache size of each tree")
+	if err := cmd.MarkFlagRequired("db-dir"); err != nil {
+		panic(err)
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return exportChangesets(params, args[0])
+	}
+	return cmd
+}
+
+func exportChangesets(params exportParams, outDir string) error {
+	stores := params.stores
+	if len(stores) == 0 {
+		if params.appDB {
+			return fmt.Errorf("stores must be set with app-db")
+		}
+		var err error
+		stores, err = listStores(params.dbDir)
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(stores)
+
+	var appDB *db.GoLevelDB
+	if params.appDB {
+		var err error
+		appDB, err = openLevelDB(appDBName, params.dbDir)
+		if err != nil {
+			return fmt.Errorf("opening %s.db: %w", appDBName, err)
+		}
+		defer appDB.Close()
+	}
+
+	trees := make([]*iavl.MutableTree, len(stores))
+	startVersion, endVersion := params.startVersion, params.endVersion
+	firstVersion, latestVersion := int64(0), int64(0)
+	for i, storeName := range stores {
+		var d db.DB
+		if appDB != nil {
+			d = db.NewPrefixDB(appDB, []byte(fmt.Sprintf("s/k:%s/", storeName)))
+		} else {
+			leveldb, err := openLevelDB(storeName, params.dbDir)
+			if err != nil {
+				return fmt.Errorf("opening store %s: %w", storeName, err)
+			}
+			defer leveldb.Close()
+			d = leveldb
+		}
+		tree := iavl.NewMutableTree(d, params.cacheSize, true, log.NewNopLogger())
+		latest, err := tree.Load()
+		if err != nil {
+			return fmt.Errorf("loading store %s: %w", storeName, err)
+		}
+		if latest == 0 {
+			return fmt.Errorf("store %s has no versions", storeName)
+		}
+		trees[i] = tree
+		latestVersion = max(latestVersion, latest)
+		if startVersion == 0 {
+			versions := tree.AvailableVersions()
+			if len(versions) == 0 {
+				return fmt.Errorf("error reading versions of store %s", storeName)
+			}
+			if firstVersion == 0 || int64(versions[0]) < firstVersion {
+				firstVersion = int64(versions[0])
+			}
+		}
+	}
+	if startVersion == 0 {
+		startVersion = firstVersion
+	}
+	if endVersion == 0 || endVersion > latestVersion {
+		endVersion = latestVersion
+	}
+	if endVersion < startVersion {
+		return fmt.Errorf("end version %d is before start version %d", endVersion, startVersion)
+	}
+
+	source := fmt.Sprintf("iavl/v1:%s", params.dbDir)
+	if params.appDB {
+		source = fmt.Sprintf("iavl/v1:%s/%s.db", params.dbDir, appDBName)
+	}
+	w, err := bench.NewChangesetWriter(outDir, source, startVersion)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for version := startVersion; version <= endVersion; version++ {
+		changes := 0
+		for i, tree := range trees {
+			storeName := stores[i]
+			// a store which doesn't have the version yet or anymore has no changes in it
+			err := tree.TraverseStateChanges(version, version, func(_ int64, changeSet *iavl.ChangeSet) error {
+				for _, pair := range changeSet.Pairs {
+					err := w.Write(&storev1beta1.StoreKVPair{
+						StoreKey: storeName,
+						Key:      pair.Key,
+						Value:    pair.Value,
+						Delete:   pair.Delete,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				changes += len(changeSet.Pairs)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error exporting version %d of store %s: %w", version, storeName, err)
+			}
+		}
+		exported := w.Version()
+		err := w.Commit()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d changes of version %d as version %d\n", changes, version, exported)
+	}
+	return nil
+}
+
+// openLevelDB opens an existing leveldb. It is not opened read-only because goleveldb can't open a db
+// with a journal read-only, but the trees are never saved and skip the fast storage upgrade.
+func openLevelDB(name, dir string) (*db.GoLevelDB, error) {
+	// goleveldb creates the dir of a missing db even with ErrorIfMissing
+	_, err := os.Stat(filepath.Join(dir, name+db.DBFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	return db.NewGoLevelDBWithOpts(name, dir, &opt.Options{ErrorIfMissing: true})
+}
+
+// listStores returns the names of the stores in a db dir in the layout of the bench command.
+func listStores(dbDir string) ([]string, error) {
+	entries, err := os.ReadDir(dbDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading db dir: %w", err)
+	}
+	var stores []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasSuffix(entry.Name(), db.DBFileSuffix) {
+			stores = append(stores, strings.TrimSuffix(entry.Name(), db.DBFileSuffix))
+		}
+	}
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("no stores found in %s", dbDir)
+	}
+	return stores, nil
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"cosmossdk.io/log"
 	"github.com/cosmos/iavl"
 	"github.com/cosmos/iavl/db"
+	ics23 "github.com/cosmos/ics23/go"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 
 	"github.com/cosmos/iavl-bench/bench"
@@ -16,6 +19,12 @@ type MultiTreeWrapper struct {
 	dbDir   string
 	version int64
 	trees   map[string]*iavl.MutableTree
+	// dbs are closed separately because closing a tree does not close its db
+	dbs []*db.GoLevelDB
+	// fastStorage is set unless the fast storage upgrade is skipped
+	fastStorage bool
+	// commitMtx keeps GetProof from reading the fast node index while Commit updates it
+	commitMtx sync.RWMutex
 }
 
 func (m *MultiTreeWrapper) Close() error {
@@ -25,6 +34,12 @@ func (m *MultiTreeWrapper) Close() error {
 			return err
 		}
 	}
+	for _, d := range m.dbs {
+		err := d.Close()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -32,10 +47,18 @@ func (m *MultiTreeWrapper) Version() int64 {
 	return m.version
 }
 
-func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+func (m *MultiTreeWrapper) getTree(storeKey string) (*iavl.MutableTree, error) {
 	tree, ok := m.trees[storeKey]
 	if !ok {
-		return fmt.Errorf("store key %s not found", storeKey)
+		return nil, fmt.Errorf("store key %s not found", storeKey)
+	}
+	return tree, nil
+}
+
+func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return err
 	}
 	if delete {
 		_, _, err := tree.Remove(key)
@@ -46,20 +69,108 @@ func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delet
 	}
 }
 
-func (m *MultiTreeWrapper) Commit() error {
-	for _, tree := range m.trees {
-		_, _, err := tree.SaveVersion()
+func (m *MultiTreeWrapper) Commit() (bench.CommitInfo, error) {
+	if m.fastStorage {
+		m.commitMtx.Lock()
+		defer m.commitMtx.Unlock()
+	}
+	storeHashes := make(map[string][]byte, len(m.trees))
+	durations := make(map[string]time.Duration, len(m.trees))
+	for storeName, tree := range m.trees {
+		start := time.Now()
+		hash, _, err := tree.SaveVersion()
 		if err != nil {
-			return err
+			return bench.CommitInfo{}, err
 		}
+		storeHashes[storeName] = hash
+		durations[storeName] = time.Since(start)
 	}
 
 	m.version++
 
-	return util.SaveVersion(m.dbDir, m.version)
+	err := util.SaveVersion(m.dbDir, m.version)
+	if err != nil {
+		return bench.CommitInfo{}, err
+	}
+	commitInfo := bench.NewCommitInfo(storeHashes)
+	commitInfo.StoreCommitDurations = durations
+	return commitInfo, nil
+}
+
+func (m *MultiTreeWrapper) Get(storeKey string, key []byte) ([]byte, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Get(key)
+}
+
+func (m *MultiTreeWrapper) Has(storeKey string, key []byte) (bool, error) {
+	value, err := m.Get(storeKey, key)
+	return value != nil, err
+}
+
+// getImmutable returns the tree of storeKey at a committed version.
+// Its nodes are safe to query concurrently with ApplyUpdate and Commit, but its Get, Has and Iterator are not
+// with fast storage enabled, as they read the fast node index which Commit updates. GetAt and IteratorAt
+// therefore traverse the tree instead, and GetProof, which uses Get, waits for Commit.
+func (m *MultiTreeWrapper) getImmutable(storeKey string, version int64) (*iavl.ImmutableTree, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	if !tree.VersionExists(version) {
+		return nil, fmt.Errorf("version %d of store %s is not available", version, storeKey)
+	}
+	return tree.GetImmutable(version)
+}
+
+func (m *MultiTreeWrapper) GetAt(storeKey string, version int64, key []byte) ([]byte, error) {
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	_, value, err := tree.GetWithIndex(key)
+	return value, err
+}
+
+func (m *MultiTreeWrapper) HasAt(storeKey string, version int64, key []byte) (bool, error) {
+	value, err := m.GetAt(storeKey, version, key)
+	return value != nil, err
+}
+
+func (m *MultiTreeWrapper) Iterator(storeKey string, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Iterator(start, end, ascending)
+}
+
+func (m *MultiTreeWrapper) IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	return iavl.NewIterator(start, end, ascending, tree), nil
+}
+
+func (m *MultiTreeWrapper) GetProof(storeKey string, version int64, key []byte) (*ics23.CommitmentProof, error) {
+	if m.fastStorage {
+		m.commitMtx.RLock()
+		defer m.commitMtx.RUnlock()
+	}
+	tree, err := m.getImmutable(storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	return tree.GetProof(key)
 }
 
 var _ bench.Tree = &MultiTreeWrapper{}
+var _ bench.Reader = &MultiTreeWrapper{}
+var _ bench.Iterable = &MultiTreeWrapper{}
+var _ bench.Prover = &MultiTreeWrapper{}
 
 type Options struct {
 	SkipFastStorageUpgrade bool `json:"skip_fast_storage_upgrade"`
@@ -67,7 +178,7 @@ type Options struct {
 }
 
 func main() {
-	bench.Run("iavl/v1", bench.RunConfig{
+	runner := bench.NewRunner("iavl/v1", bench.RunConfig{
 		OptionsType: &Options{},
 		TreeLoader: func(params bench.LoaderParams) (bench.Tree, error) {
 			opts := params.TreeOptions.(*Options)
@@ -77,6 +188,7 @@ func main() {
 				return nil, err
 			}
 			trees := make(map[string]*iavl.MutableTree)
+			var dbs []*db.GoLevelDB
 			//logger := util.NewSlogWrapper(params.Logger)
 			// logging is very noisy, use a nop logger
 			logger := log.NewNopLogger()
@@ -85,6 +197,7 @@ func main() {
 				if err != nil {
 					return nil, err
 				}
+				dbs = append(dbs, d)
 				tree := iavl.NewMutableTree(d, opts.CacheSize, opts.SkipFastStorageUpgrade, logger)
 				if version != 0 {
 					_, err := tree.LoadVersion(version)
@@ -95,10 +208,14 @@ func main() {
 				trees[storeName] = tree
 			}
 			return &MultiTreeWrapper{
-				trees:   trees,
-				version: version,
-				dbDir:   dbDir,
+				trees:       trees,
+				dbs:         dbs,
+				version:     version,
+				dbDir:       dbDir,
+				fastStorage: !opts.SkipFastStorageUpgrade,
 			}, nil
 		},
 	})
+	runner.AddCommand(newExportCommand())
+	runner.Run()
 }
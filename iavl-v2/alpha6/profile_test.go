@@ -9,15 +9,25 @@ import (
 
 func TestProfile(t *testing.T) {
 	runner := iavl_v2.Runner("iavl/v2-alpha6")
+	changesetDir := os.Getenv("IAVL_BENCH_CHANGESET_DIR")
+	if changesetDir == "" {
+		t.Skip("IAVL_BENCH_CHANGESET_DIR is not set")
+	}
 	dir, err := os.MkdirTemp("", "iavl-bench-profile")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer os.RemoveAll(dir)
+	// the profiles are kept if IAVL_BENCH_PROFILE_DIR is set
+	profileDir := os.Getenv("IAVL_BENCH_PROFILE_DIR")
+	if profileDir == "" {
+		profileDir = t.TempDir()
+	}
 	runner.SetArgs([]string{
 		"bench",
-		"--changeset-dir=/Users/arc/iavl-bench-data/testdata",
+		"--changeset-dir=" + changesetDir,
 		"--db-dir=" + dir,
+		"--profile-dir=" + profileDir,
 	})
 	runner.Run()
 }
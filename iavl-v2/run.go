@@ -1,10 +1,14 @@
 package iavl_v2
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/cosmos/iavl/v2"
+	ics23 "github.com/cosmos/ics23/go"
 
 	"github.com/cosmos/iavl-bench/bench"
 	"github.com/cosmos/iavl-bench/bench/util"
@@ -14,6 +18,29 @@ type MultiTreeWrapper struct {
 	dbDir   string
 	version int64
 	trees   map[string]*iavl.Tree
+	// mtx serializes all reads with ApplyUpdate and Commit once concurrent reads are enabled. The trees share their
+	// sqlite connections and node pool between reads and writes, so they are not safe for concurrent use.
+	mtx             sync.Mutex
+	concurrentReads bool
+}
+
+// EnableConcurrentReads makes the reads and writes take mtx. The reads are serialized with the writes,
+// so query latencies include waiting for them.
+func (m *MultiTreeWrapper) EnableConcurrentReads() bool {
+	m.concurrentReads = true
+	return true
+}
+
+func (m *MultiTreeWrapper) lock() {
+	if m.concurrentReads {
+		m.mtx.Lock()
+	}
+}
+
+func (m *MultiTreeWrapper) unlock() {
+	if m.concurrentReads {
+		m.mtx.Unlock()
+	}
 }
 
 func (m *MultiTreeWrapper) Close() error {
@@ -30,11 +57,21 @@ func (m *MultiTreeWrapper) Version() int64 {
 	return m.version
 }
 
-func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+func (m *MultiTreeWrapper) getTree(storeKey string) (*iavl.Tree, error) {
 	tree, ok := m.trees[storeKey]
 	if !ok {
-		return fmt.Errorf("store key %s not found", storeKey)
+		return nil, fmt.Errorf("store key %s not found", storeKey)
 	}
+	return tree, nil
+}
+
+func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return err
+	}
+	m.lock()
+	defer m.unlock()
 	if delete {
 		_, _, err := tree.Remove(key)
 		return err
@@ -44,20 +81,202 @@ func (m *MultiTreeWrapper) ApplyUpdate(storeKey string, key, value []byte, delet
 	}
 }
 
-func (m *MultiTreeWrapper) Commit() error {
-	for _, tree := range m.trees {
-		_, _, err := tree.SaveVersion()
+func (m *MultiTreeWrapper) Commit() (bench.CommitInfo, error) {
+	storeHashes := make(map[string][]byte, len(m.trees))
+	durations := make(map[string]time.Duration, len(m.trees))
+	m.lock()
+	for storeName, tree := range m.trees {
+		start := time.Now()
+		hash, _, err := tree.SaveVersion()
 		if err != nil {
-			return err
+			m.unlock()
+			return bench.CommitInfo{}, err
 		}
+		storeHashes[storeName] = hash
+		durations[storeName] = time.Since(start)
 	}
+	m.unlock()
 
 	m.version++
 
-	return util.SaveVersion(m.dbDir, m.version)
+	err := util.SaveVersion(m.dbDir, m.version)
+	if err != nil {
+		return bench.CommitInfo{}, err
+	}
+	commitInfo := bench.NewCommitInfo(storeHashes)
+	commitInfo.StoreCommitDurations = durations
+	return commitInfo, nil
+}
+
+// Get reads the working tree in alpha5, but only the last committed version in later versions where
+// uncommitted changes are staged separately.
+func (m *MultiTreeWrapper) Get(storeKey string, key []byte) ([]byte, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	m.lock()
+	defer m.unlock()
+	return tree.Get(key)
+}
+
+func (m *MultiTreeWrapper) Has(storeKey string, key []byte) (bool, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return false, err
+	}
+	m.lock()
+	defer m.unlock()
+	return tree.Has(key)
+}
+
+// recentGetter, recentIterable, readonlyCloner and versionedProver are only implemented by some versions of iavl/v2,
+// so we detect them at runtime (because of version incompatibility).
+type recentGetter interface {
+	GetRecent(version int64, key []byte) (bool, []byte, error)
+}
+
+type recentIterable interface {
+	IterateRecent(version int64, start, end []byte, ascending bool) (bool, iavl.Iterator)
+}
+
+type readonlyCloner interface {
+	ReadonlyClone() (*iavl.Tree, error)
+}
+
+type versionedProver interface {
+	GetProof(version int64, key []byte) (*ics23.CommitmentProof, error)
+}
+
+func (m *MultiTreeWrapper) GetAt(storeKey string, version int64, key []byte) (value []byte, err error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	m.lock()
+	defer m.unlock()
+	// the latest versions are still held in memory
+	if recent, ok := any(tree).(recentGetter); ok {
+		found, value, err := recent.GetRecent(version, key)
+		if found || err != nil {
+			return value, err
+		}
+	}
+	clone, err := m.loadClone(tree, storeKey, version)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, clone.Close())
+	}()
+	return clone.Get(key)
+}
+
+// loadClone opens a read-only copy of tree at version, which must be closed by the caller.
+func (m *MultiTreeWrapper) loadClone(tree *iavl.Tree, storeKey string, version int64) (*iavl.Tree, error) {
+	cloner, ok := any(tree).(readonlyCloner)
+	if !ok {
+		return nil, fmt.Errorf("historical reads are not supported by this version of iavl/v2")
+	}
+	clone, err := cloner.ReadonlyClone()
+	if err != nil {
+		return nil, err
+	}
+	err = clone.LoadVersion(version)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("loading version %d of store %s: %w", version, storeKey, err), clone.Close())
+	}
+	return clone, nil
+}
+
+func (m *MultiTreeWrapper) HasAt(storeKey string, version int64, key []byte) (bool, error) {
+	value, err := m.GetAt(storeKey, version, key)
+	return value != nil, err
+}
+
+func iterate(tree *iavl.Tree, start, end []byte, ascending bool) (iavl.Iterator, error) {
+	if ascending {
+		return tree.Iterator(start, end, false)
+	}
+	return tree.ReverseIterator(start, end)
+}
+
+func (m *MultiTreeWrapper) Iterator(storeKey string, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	m.lock()
+	unlock := func() error {
+		m.unlock()
+		return nil
+	}
+	itr, err := iterate(tree, start, end, ascending)
+	if err != nil {
+		return nil, errors.Join(err, unlock())
+	}
+	return &closeHookIterator{Iterator: itr, onClose: unlock}, nil
+}
+
+func (m *MultiTreeWrapper) IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	// the iterator loads nodes lazily, so writes are held off until it is closed
+	m.lock()
+	unlock := func() error {
+		m.unlock()
+		return nil
+	}
+	if recent, ok := any(tree).(recentIterable); ok {
+		found, itr := recent.IterateRecent(version, start, end, ascending)
+		if found {
+			return &closeHookIterator{Iterator: itr, onClose: unlock}, nil
+		}
+	}
+	clone, err := m.loadClone(tree, storeKey, version)
+	if err != nil {
+		return nil, errors.Join(err, unlock())
+	}
+	itr, err := iterate(clone, start, end, ascending)
+	if err != nil {
+		return nil, errors.Join(err, clone.Close(), unlock())
+	}
+	return &closeHookIterator{Iterator: itr, onClose: func() error {
+		return errors.Join(clone.Close(), unlock())
+	}}, nil
+}
+
+// closeHookIterator releases the resources backing an iterator once it is closed.
+type closeHookIterator struct {
+	iavl.Iterator
+	onClose func() error
+}
+
+func (c *closeHookIterator) Close() error {
+	return errors.Join(c.Iterator.Close(), c.onClose())
+}
+
+func (m *MultiTreeWrapper) GetProof(storeKey string, version int64, key []byte) (*ics23.CommitmentProof, error) {
+	tree, err := m.getTree(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	prover, ok := any(tree).(versionedProver)
+	if !ok {
+		return nil, fmt.Errorf("proofs are not supported by this version of iavl/v2")
+	}
+	m.lock()
+	defer m.unlock()
+	return prover.GetProof(version, key)
 }
 
 var _ bench.Tree = &MultiTreeWrapper{}
+var _ bench.Reader = &MultiTreeWrapper{}
+var _ bench.Iterable = &MultiTreeWrapper{}
+var _ bench.Prover = &MultiTreeWrapper{}
+var _ bench.ConcurrentReader = &MultiTreeWrapper{}
 
 type Options struct {
 	CheckpointInterval int64 `json:"checkpoint_interval"`
@@ -1,6 +1,12 @@
 package runner
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	ics23 "github.com/cosmos/ics23/go"
 	"github.com/crypto-org-chain/cronos/memiavl"
 
 	"github.com/cosmos/iavl-bench/bench"
@@ -8,7 +14,29 @@ import (
 )
 
 type DBWrapper struct {
-	db *memiavl.DB
+	db   *memiavl.DB
+	dir  string
+	opts memiavl.Options
+
+	// committed are copies of the last two committed versions, newest first, which can be queried while the next
+	// version is applied. Queries of the older one may still be in flight right after a commit.
+	// They are only taken with concurrentReads, as memiavl copies the nodes of the tree on write from then on.
+	committedMtx sync.RWMutex
+	committed    [2]*memiavl.MultiTree
+	// concurrentReads is set by EnableConcurrentReads.
+	concurrentReads bool
+	// rewritePending is set from the commit starting a background snapshot rewrite until the commit switching
+	// to the new snapshot, which unmaps the old one, so committedMtx holds off queries of the copies during it.
+	rewritePending bool
+	// dirty is set while there are uncommitted changes.
+	dirty bool
+}
+
+// EnableConcurrentReads makes Commit keep copies of the last committed versions, which queries read
+// while the next version is applied.
+func (d *DBWrapper) EnableConcurrentReads() bool {
+	d.concurrentReads = true
+	return false
 }
 
 func (d *DBWrapper) Close() error {
@@ -29,15 +57,236 @@ func (d *DBWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool)
 			},
 		},
 	}
+	d.dirty = true
 	return d.db.ApplyChangeSet(storeKey, changeSet)
 }
 
-func (d *DBWrapper) Commit() error {
-	_, err := d.db.Commit()
-	return err
+func (d *DBWrapper) Commit() (bench.CommitInfo, error) {
+	locked := d.concurrentReads && d.rewritePending
+	if locked {
+		d.committedMtx.Lock()
+	}
+	snapshotVersion := d.db.SnapshotVersion()
+	version, err := d.db.Commit()
+	if locked {
+		d.committedMtx.Unlock()
+	}
+	if err != nil {
+		return bench.CommitInfo{}, err
+	}
+	d.dirty = false
+	if d.concurrentReads {
+		if d.db.SnapshotVersion() != snapshotVersion {
+			d.rewritePending = false
+		}
+		if version%int64(d.snapshotInterval()) == 0 {
+			d.rewritePending = true
+		}
+		// the copy has a nil cache because the cache is not thread-safe
+		latest := d.db.MultiTree.Copy(0)
+		d.committedMtx.Lock()
+		previous := d.committed[0]
+		if previous != nil && previous.SnapshotVersion() != latest.SnapshotVersion() {
+			// the snapshot backing the previous copy was closed when the db switched to a new one
+			previous = nil
+		}
+		d.committed = [2]*memiavl.MultiTree{latest, previous}
+		d.committedMtx.Unlock()
+	}
+	storeInfos := d.db.LastCommitInfo().StoreInfos
+	storeHashes := make(map[string][]byte, len(storeInfos))
+	for _, storeInfo := range storeInfos {
+		storeHashes[storeInfo.Name] = storeInfo.CommitId.Hash
+	}
+	return bench.NewCommitInfo(storeHashes), nil
+}
+
+// snapshotInterval is the interval of the versions at which memiavl starts a background snapshot rewrite.
+func (d *DBWrapper) snapshotInterval() uint32 {
+	if d.opts.SnapshotInterval == 0 {
+		return memiavl.DefaultSnapshotInterval
+	}
+	return d.opts.SnapshotInterval
+}
+
+// treeLookup is implemented by both memiavl.DB and memiavl.MultiTree.
+type treeLookup interface {
+	TreeByName(name string) *memiavl.Tree
+}
+
+func (d *DBWrapper) getTree(db treeLookup, storeKey string) (*memiavl.Tree, error) {
+	tree := db.TreeByName(storeKey)
+	if tree == nil {
+		return nil, fmt.Errorf("store key %s not found", storeKey)
+	}
+	return tree, nil
+}
+
+func (d *DBWrapper) Get(storeKey string, key []byte) ([]byte, error) {
+	tree, err := d.getTree(d.db, storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Get(key), nil
+}
+
+func (d *DBWrapper) Has(storeKey string, key []byte) (bool, error) {
+	tree, err := d.getTree(d.db, storeKey)
+	if err != nil {
+		return false, err
+	}
+	return tree.Has(key), nil
+}
+
+// committedAt returns the in-memory copy of version if there is one, holding a read lock on it which the caller must release.
+// Without concurrent reads, the db itself is returned for the last committed version if nothing was applied since.
+func (d *DBWrapper) committedAt(version int64) (*memiavl.MultiTree, bool) {
+	d.committedMtx.RLock()
+	if !d.concurrentReads && !d.dirty && version == d.db.Version() {
+		return &d.db.MultiTree, true
+	}
+	for _, committed := range d.committed {
+		if committed != nil && committed.Version() == version {
+			return committed, true
+		}
+	}
+	d.committedMtx.RUnlock()
+	return nil, false
+}
+
+// GetAt reads the last committed versions from in-memory copies. Older versions are read from
+// a separate read-only instance of the db loaded at the target version, like a query node would.
+func (d *DBWrapper) GetAt(storeKey string, version int64, key []byte) (value []byte, err error) {
+	if committed, ok := d.committedAt(version); ok {
+		defer d.committedMtx.RUnlock()
+		tree, err := d.getTree(committed, storeKey)
+		if err != nil {
+			return nil, err
+		}
+		value := tree.Get(key)
+		if d.opts.ZeroCopy {
+			// the value may point into a snapshot which is unmapped once the lock is released
+			value = bytes.Clone(value)
+		}
+		return value, nil
+	}
+
+	db, err := d.loadReadOnly(version)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+	tree, err := d.getTree(db, storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Get(key), nil
+}
+
+// loadReadOnly loads a read-only instance of the db at version, which must be closed by the caller.
+func (d *DBWrapper) loadReadOnly(version int64) (*memiavl.DB, error) {
+	opts := d.opts
+	opts.ReadOnly = true
+	opts.CreateIfMissing = false
+	opts.TargetVersion = uint32(version)
+	// values must stay valid after the instance is closed
+	opts.ZeroCopy = false
+	db, err := memiavl.Load(d.dir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("loading version %d: %w", version, err)
+	}
+	return db, nil
+}
+
+func (d *DBWrapper) HasAt(storeKey string, version int64, key []byte) (bool, error) {
+	value, err := d.GetAt(storeKey, version, key)
+	return value != nil, err
+}
+
+func (d *DBWrapper) Iterator(storeKey string, start, end []byte, ascending bool) (bench.Iterator, error) {
+	tree, err := d.getTree(d.db, storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Iterator(start, end, ascending), nil
+}
+
+func (d *DBWrapper) IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (bench.Iterator, error) {
+	if committed, ok := d.committedAt(version); ok {
+		tree, err := d.getTree(committed, storeKey)
+		if err != nil {
+			d.committedMtx.RUnlock()
+			return nil, err
+		}
+		// the copy must not be unmapped while the iterator is open
+		return &closeHookIterator{Iterator: tree.Iterator(start, end, ascending), onClose: func() error {
+			d.committedMtx.RUnlock()
+			return nil
+		}}, nil
+	}
+
+	db, err := d.loadReadOnly(version)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := d.getTree(db, storeKey)
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return &closeHookIterator{Iterator: tree.Iterator(start, end, ascending), onClose: db.Close}, nil
+}
+
+// GetProof generates proofs of the last committed versions from the in-memory copies and
+// of older versions from a read-only instance of the db, like GetAt.
+func (d *DBWrapper) GetProof(storeKey string, version int64, key []byte) (proof *ics23.CommitmentProof, err error) {
+	if committed, ok := d.committedAt(version); ok {
+		defer d.committedMtx.RUnlock()
+		tree, err := d.getTree(committed, storeKey)
+		if err != nil {
+			return nil, err
+		}
+		return treeProof(tree, key)
+	}
+
+	db, err := d.loadReadOnly(version)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+	tree, err := d.getTree(db, storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return treeProof(tree, key)
+}
+
+// treeProof returns an existence proof of key if it exists in tree and a non-existence proof otherwise.
+func treeProof(tree *memiavl.Tree, key []byte) (*ics23.CommitmentProof, error) {
+	if tree.Has(key) {
+		return tree.GetMembershipProof(key)
+	}
+	return tree.GetNonMembershipProof(key)
+}
+
+// closeHookIterator releases the resources backing an iterator once it is closed.
+type closeHookIterator struct {
+	*memiavl.Iterator
+	onClose func() error
+}
+
+func (c *closeHookIterator) Close() error {
+	return errors.Join(c.Iterator.Close(), c.onClose())
 }
 
 var _ bench.Tree = &DBWrapper{}
+var _ bench.Reader = &DBWrapper{}
+var _ bench.Iterable = &DBWrapper{}
+var _ bench.Prover = &DBWrapper{}
+var _ bench.ConcurrentReader = &DBWrapper{}
 
 type Options struct {
 	SnapshotKeepRecent uint32 `json:"snapshot_keep_recent"`
@@ -75,7 +324,7 @@ func Runner() bench.Runner {
 			if err != nil {
 				return nil, err
 			}
-			return &DBWrapper{db: db}, nil
+			return &DBWrapper{db: db, dir: params.TreeDir, opts: opts}, nil
 		},
 	})
 }
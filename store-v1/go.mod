@@ -5,6 +5,7 @@ go 1.25.0
 require (
 	cosmossdk.io/store v1.1.2
 	github.com/cosmos/iavl-bench/bench v0.0.4
+	github.com/cosmos/ics23/go v0.11.0
 )
 
 replace github.com/cosmos/iavl-bench/bench => ../bench
@@ -30,7 +31,6 @@ require (
 	github.com/cosmos/cosmos-db v1.1.1 // indirect
 	github.com/cosmos/cosmos-proto v1.0.0-beta.5 // indirect
 	github.com/cosmos/gogoproto v1.7.0 // indirect
-	github.com/cosmos/ics23/go v0.11.0 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/ebitengine/purego v0.8.4 // indirect
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"cosmossdk.io/store/types"
+	ics23 "github.com/cosmos/ics23/go"
 
 	"github.com/cosmos/iavl-bench/bench"
 )
@@ -43,10 +44,18 @@ func (s *CommitMultiStoreWrapper) Version() int64 {
 	return s.store.LatestVersion()
 }
 
-func (s *CommitMultiStoreWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+func (s *CommitMultiStoreWrapper) getStoreKey(storeKey string) (types.StoreKey, error) {
 	sk, ok := s.storeKeys[storeKey]
 	if !ok {
-		return fmt.Errorf("store key %s not found", storeKey)
+		return nil, fmt.Errorf("store key %s not found", storeKey)
+	}
+	return sk, nil
+}
+
+func (s *CommitMultiStoreWrapper) ApplyUpdate(storeKey string, key, value []byte, delete bool) error {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return err
 	}
 	store := s.store.GetKVStore(sk)
 	if delete {
@@ -57,9 +66,113 @@ func (s *CommitMultiStoreWrapper) ApplyUpdate(storeKey string, key, value []byte
 	return nil
 }
 
-func (s *CommitMultiStoreWrapper) Commit() error {
+func (s *CommitMultiStoreWrapper) Commit() (bench.CommitInfo, error) {
 	_ = s.store.Commit()
+	storeHashes := make(map[string][]byte, len(s.storeKeys))
+	for name, sk := range s.storeKeys {
+		storeHashes[name] = s.store.GetCommitKVStore(sk).LastCommitID().Hash
+	}
+	return bench.NewCommitInfo(storeHashes), nil
+}
+
+func (s *CommitMultiStoreWrapper) Get(storeKey string, key []byte) ([]byte, error) {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetKVStore(sk).Get(key), nil
+}
+
+func (s *CommitMultiStoreWrapper) Has(storeKey string, key []byte) (bool, error) {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return false, err
+	}
+	return s.store.GetKVStore(sk).Has(key), nil
+}
+
+func (s *CommitMultiStoreWrapper) GetAt(storeKey string, version int64, key []byte) ([]byte, error) {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	cms, err := s.store.CacheMultiStoreWithVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("loading version %d: %w", version, err)
+	}
+	return cms.GetKVStore(sk).Get(key), nil
+}
+
+func (s *CommitMultiStoreWrapper) HasAt(storeKey string, version int64, key []byte) (bool, error) {
+	value, err := s.GetAt(storeKey, version, key)
+	return value != nil, err
+}
+
+func iterate(store types.KVStore, start, end []byte, ascending bool) types.Iterator {
+	if ascending {
+		return store.Iterator(start, end)
+	}
+	return store.ReverseIterator(start, end)
+}
+
+func (s *CommitMultiStoreWrapper) Iterator(storeKey string, start, end []byte, ascending bool) (bench.Iterator, error) {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	return iterate(s.store.GetKVStore(sk), start, end, ascending), nil
+}
+
+func (s *CommitMultiStoreWrapper) IteratorAt(storeKey string, version int64, start, end []byte, ascending bool) (bench.Iterator, error) {
+	sk, err := s.getStoreKey(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	cms, err := s.store.CacheMultiStoreWithVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("loading version %d: %w", version, err)
+	}
+	return cacheIterator{iterate(cms.GetKVStore(sk), start, end, ascending)}, nil
+}
+
+// cacheIterator wraps the iterator of a cache store, which reports an error once it is exhausted
+// but has no other error conditions.
+type cacheIterator struct {
+	types.Iterator
+}
+
+func (c cacheIterator) Error() error {
 	return nil
 }
 
+// GetProof queries the store like a client querying a node with prove set, and returns the store level
+// ICS23 proof, leaving out the proof of the store hash in the multi store.
+func (s *CommitMultiStoreWrapper) GetProof(storeKey string, version int64, key []byte) (*ics23.CommitmentProof, error) {
+	queryable, ok := s.store.(types.Queryable)
+	if !ok {
+		return nil, fmt.Errorf("store of type %T does not support queries", s.store)
+	}
+	res, err := queryable.Query(&types.RequestQuery{
+		Path:   "/" + storeKey + "/key",
+		Data:   key,
+		Height: version,
+		Prove:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying version %d: %w", version, err)
+	}
+	if res.ProofOps == nil || len(res.ProofOps.Ops) == 0 {
+		return nil, fmt.Errorf("query of version %d returned no proof", version)
+	}
+	proof := &ics23.CommitmentProof{}
+	err = proof.Unmarshal(res.ProofOps.Ops[0].Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding proof: %w", err)
+	}
+	return proof, nil
+}
+
 var _ bench.Tree = &CommitMultiStoreWrapper{}
+var _ bench.Reader = &CommitMultiStoreWrapper{}
+var _ bench.Iterable = &CommitMultiStoreWrapper{}
+var _ bench.Prover = &CommitMultiStoreWrapper{}